@@ -189,7 +189,7 @@ func connect(cfg *config.Config, connectionString string) (*sql.DB, error) {
 	return db, err
 }
 
-func initialiseDatabase(db *sql.DB) error {
+func initialiseDatabase(db *sql.DB) (err error) {
 	f := functionInitialiseDatabase
 	ctx := context.Background()
 
@@ -199,9 +199,9 @@ func initialiseDatabase(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer model.EndTransaction(ctx, tx, db, err)
+	defer model.EndTransaction(ctx, tx, &err)
 
-	err = initialiseDatabaseTx(ctx, db)
+	err = initialiseDatabaseTx(ctx, tx)
 	if err != nil {
 		message := "Could not begin a new transaction"
 		f.DumpError(err, message)
@@ -211,7 +211,7 @@ func initialiseDatabase(db *sql.DB) error {
 	return nil
 }
 
-func initialiseDatabaseTx(ctx context.Context, db *sql.DB) error {
+func initialiseDatabaseTx(ctx context.Context, db model.Querier) error {
 	f := functionInitialiseDatabaseTx
 	f.DebugVerbose("")
 
@@ -233,7 +233,7 @@ func initialiseDatabaseTx(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
-func createTables(ctx context.Context, db *sql.DB) error {
+func createTables(ctx context.Context, db model.Querier) error {
 	f := functionCreateTables
 	f.DebugVerbose("")
 
@@ -247,7 +247,8 @@ func createTables(ctx context.Context, db *sql.DB) error {
 			email VARCHAR(255) NOT NULL UNIQUE,
 			phone VARCHAR(32) NOT NULL UNIQUE,
 			hash VARCHAR(255) NOT NULL,	
-			status VARCHAR(32) NOT NULL
+			status VARCHAR(32) NOT NULL,
+			rating INT NOT NULL DEFAULT ` + fmt.Sprint(model.InitialRating) + `
 		 )`
 	_, err := db.ExecContext(ctx, sqlStatement)
 	if err != nil {
@@ -271,7 +272,9 @@ func createTables(ctx context.Context, db *sql.DB) error {
 	sqlStatement = `
 		CREATE TABLE ` + model.CourtTable + ` (
 			id SERIAL PRIMARY KEY,
-			name VARCHAR(255)
+			name VARCHAR(255),
+			capacity INT NOT NULL DEFAULT ` + fmt.Sprint(model.DefaultCourtCapacity) + `,
+			game_minutes INT NOT NULL DEFAULT 0
 		 )`
 	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
@@ -287,6 +290,7 @@ func createTables(ctx context.Context, db *sql.DB) error {
 			court    INT NOT NULL,
 			person   INT NOT NULL,
 			position INT NOT NULL,		
+			start    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 
 			PRIMARY KEY (court, person, position),
 
@@ -321,11 +325,35 @@ func createTables(ctx context.Context, db *sql.DB) error {
 		return err
 	}
 
+	// Create the call table. A call goes when its player leaves the court
+	sqlStatement = `
+		CREATE TABLE ` + model.CallTable + ` (
+			person   INT PRIMARY KEY,
+			court    INT NOT NULL,
+			position INT NOT NULL,
+			called   TIMESTAMP WITH TIME ZONE NOT NULL,
+			start    TIMESTAMP WITH TIME ZONE,
+			place    INT NOT NULL DEFAULT 0,
+			priority INT NOT NULL DEFAULT 0,
+			CONSTRAINT playing FOREIGN KEY(court, person, position) REFERENCES playing(court, person, position) ON DELETE CASCADE
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create call table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
 	// Create the waiting table
 	sqlStatement = `
 		CREATE TABLE ` + model.WaitingTable + ` (
-			person INT PRIMARY KEY,
-			start  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			person   INT PRIMARY KEY,
+			start    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			paused   BOOLEAN NOT NULL DEFAULT FALSE,
+			place    INT NOT NULL DEFAULT 0,
+			priority INT NOT NULL DEFAULT 0,
+			notified INT NOT NULL DEFAULT 0,
 			CONSTRAINT person FOREIGN KEY(person) REFERENCES person(id)
 		 )`
 	_, err = db.ExecContext(ctx, sqlStatement)
@@ -337,7 +365,7 @@ func createTables(ctx context.Context, db *sql.DB) error {
 	}
 
 	// Create the waiting index
-	sqlStatement = "CREATE INDEX " + model.WaitingIndex + " ON " + model.WaitingTable + " ( start )"
+	sqlStatement = "CREATE INDEX " + model.WaitingIndex + " ON " + model.WaitingTable + " ( priority DESC, place )"
 	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not create playing_court index"
@@ -346,16 +374,205 @@ func createTables(ctx context.Context, db *sql.DB) error {
 		return err
 	}
 
+	// Create the notification table
+	sqlStatement = `
+		CREATE TABLE ` + model.NotificationTable + ` (
+			id        SERIAL PRIMARY KEY,
+			person    INT NOT NULL,
+			created   TIMESTAMP WITH TIME ZONE NOT NULL,
+			kind      VARCHAR(32) NOT NULL,
+			text      VARCHAR(255) NOT NULL,
+			published BOOLEAN NOT NULL DEFAULT FALSE,
+			CONSTRAINT person FOREIGN KEY(person) REFERENCES person(id) ON DELETE CASCADE
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create notification table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the notification_person index
+	sqlStatement = "CREATE INDEX notification_person ON " + model.NotificationTable + " ( person, id )"
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create notification_person index"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the session table
+	sqlStatement = `
+		CREATE TABLE ` + model.SessionTable + ` (
+			id           SERIAL PRIMARY KEY,
+			opened       TIMESTAMP WITH TIME ZONE NOT NULL,
+			closed       TIMESTAMP WITH TIME ZONE,
+			game_minutes INT NOT NULL DEFAULT 0
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create session table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the session_open index, which allows only one open session
+	sqlStatement = "CREATE UNIQUE INDEX session_open ON " + model.SessionTable + " ( (closed IS NULL) ) WHERE closed IS NULL"
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create session_open index"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the attendance table. The person is not a foreign key, so the history outlives deleted people
+	sqlStatement = `
+		CREATE TABLE ` + model.AttendanceTable + ` (
+			session INT NOT NULL,
+			person  INT NOT NULL,
+			arrived TIMESTAMP WITH TIME ZONE NOT NULL,
+
+			PRIMARY KEY (session, person),
+
+			CONSTRAINT session FOREIGN KEY(session) REFERENCES session(id) ON DELETE CASCADE
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create attendance table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the game table. The court is not a foreign key, so the history outlives deleted courts
+	sqlStatement = `
+		CREATE TABLE ` + model.GameTable + ` (
+			id         SERIAL PRIMARY KEY,
+			court      INT NOT NULL,
+			court_name VARCHAR(255),
+			session    INT,
+			capacity   INT NOT NULL,
+			start      TIMESTAMP WITH TIME ZONE NOT NULL,
+			finish     TIMESTAMP WITH TIME ZONE NOT NULL,
+
+			CONSTRAINT session FOREIGN KEY(session) REFERENCES session(id)
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create game table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the game_finish index
+	sqlStatement = "CREATE INDEX game_finish ON " + model.GameTable + " ( finish )"
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create game_finish index"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the game_player table. The person is not a foreign key, so the history outlives deleted people
+	sqlStatement = `
+		CREATE TABLE ` + model.GamePlayerTable + ` (
+			game     INT NOT NULL,
+			person   INT NOT NULL,
+			position INT NOT NULL,
+
+			PRIMARY KEY (game, position),
+
+			CONSTRAINT game FOREIGN KEY(game) REFERENCES game(id) ON DELETE CASCADE
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create game_player table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the game_player_person index
+	sqlStatement = "CREATE INDEX game_player_person ON " + model.GamePlayerTable + " ( person )"
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create game_player_person index"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	// Create the score table
+	sqlStatement = `
+		CREATE TABLE ` + model.ScoreTable + ` (
+			game       INT NOT NULL,
+			set_number INT NOT NULL,
+			team1      INT NOT NULL,
+			team2      INT NOT NULL,
+
+			PRIMARY KEY (game, set_number),
+
+			CONSTRAINT game FOREIGN KEY(game) REFERENCES game(id) ON DELETE CASCADE
+		 )`
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not create score table"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
 	f.DebugInfo("Successfully created Tables")
 	return nil
 }
 
-func dropTables(ctx context.Context, db *sql.DB) error {
+func dropTables(ctx context.Context, db model.Querier) error {
 	f := functionDropTables
 	f.DebugVerbose("")
 
 	// Drop the tables
-	err := dropTable(ctx, db, model.PlayingTable)
+	err := dropTable(ctx, db, model.ScoreTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.GamePlayerTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.GameTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.AttendanceTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.SessionTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.NotificationTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.CallTable)
+	if err != nil {
+		return err
+	}
+
+	err = dropTable(ctx, db, model.PlayingTable)
 	if err != nil {
 		return err
 	}
@@ -378,7 +595,7 @@ func dropTables(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
-func dropTable(ctx context.Context, db *sql.DB, table string) error {
+func dropTable(ctx context.Context, db model.Querier, table string) error {
 	f := functionDropTable
 
 	exists, err := tableExists(ctx, db, table)
@@ -419,11 +636,11 @@ func dropTable(ctx context.Context, db *sql.DB, table string) error {
 	return err
 }
 
-func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+func tableExists(ctx context.Context, db model.Querier, table string) (bool, error) {
 	f := functionTableExists
 
 	sqlStatement := fmt.Sprintf("SELECT EXISTS ( SELECT FROM %s WHERE schemaname = '%s' AND tablename = '%s' )", "pg_tables", "public", table)
-	row := db.QueryRow(sqlStatement)
+	row := db.QueryRowContext(ctx, sqlStatement)
 
 	var exists bool
 	err := row.Scan(&exists)
@@ -437,7 +654,7 @@ func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
 	return exists, nil
 }
 
-func createAdminUser(ctx context.Context, db *sql.DB) error {
+func createAdminUser(ctx context.Context, db model.Querier) error {
 	f := functionCreateAdminUser
 	f.DebugVerbose("")
 
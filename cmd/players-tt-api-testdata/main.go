@@ -70,16 +70,23 @@ func main() {
 		f.DumpError(err, message)
 		os.Exit(1)
 	}
-	defer model.EndTransaction(ctx, tx, db, err)
+	defer model.EndTransaction(ctx, tx, &err)
 
-	_, err = makePeopleTx(db)
+	// the players join the waiting list, which needs an open session
+	_, err = model.OpenSessionTx(ctx, tx, 0)
+	if err != nil {
+		f.Errorf("Error opening a session")
+		os.Exit(1)
+	}
+
+	_, err = makePeopleTx(ctx, tx)
 	if err != nil {
 		f.Errorf("Error making people")
 		os.Exit(1)
 	}
 
 	/* courtIDs */
-	_, err = makeCourtsTx(db)
+	_, err = makeCourtsTx(ctx, tx)
 	if err != nil {
 		f.Errorf("Error making courts")
 		os.Exit(1)
@@ -92,7 +99,7 @@ type PersonData struct {
 	Status string
 }
 
-func makePeopleTx(db *sql.DB) (map[int]int, error) {
+func makePeopleTx(ctx context.Context, tx *sql.Tx) (map[int]int, error) {
 	f := functionMakePeopleTx
 
 	peopleData := []PersonData{
@@ -129,20 +136,9 @@ func makePeopleTx(db *sql.DB) (map[int]int, error) {
 			os.Exit(1)
 		}
 
-		ctx := context.Background()
-
-		// Create a new context, and begin a transaction
-		tx, err := db.BeginTx(ctx, nil)
-		if err != nil {
-			message := "Could not begin a new transaction"
-			f.DumpError(err, message)
-			os.Exit(1)
-		}
-		defer model.EndTransaction(ctx, tx, db, err)
-
 		p.Status = r.Status
 
-		err = p.SavePersonTx(ctx, db)
+		err = p.SavePersonTx(ctx, tx)
 		if err != nil {
 			message := fmt.Sprintf("Could not save person: firstName: %s, lastname: %s, email: %s", p.FirstName, p.LastName, p.Email)
 			f.Errorf(message)
@@ -163,7 +159,7 @@ func makePeopleTx(db *sql.DB) (map[int]int, error) {
 		f.DebugInfo("    Status:    %s", p.Status)
 
 		fix := true
-		_, err = p.CheckConistencyPerson(ctx, db, fix)
+		_, err = p.CheckConistencyPerson(ctx, tx, fix)
 		if err != nil {
 			message := fmt.Sprintf("Inconsistent data: firstName: %s, lastname: %s, email: %s", p.FirstName, p.LastName, p.Email)
 			f.Errorf(message)
@@ -180,7 +176,7 @@ type CourtData struct {
 	Name string
 }
 
-func makeCourtsTx(db *sql.DB) (map[int]int, error) {
+func makeCourtsTx(ctx context.Context, tx *sql.Tx) (map[int]int, error) {
 	f := functionMakeCourtsTx
 
 	courtsData := []CourtData{
@@ -193,7 +189,7 @@ func makeCourtsTx(db *sql.DB) (map[int]int, error) {
 
 		court := model.Court{Name: c.Name}
 
-		err := court.SaveCourt(db)
+		err := court.SaveCourtTx(ctx, tx)
 		if err != nil {
 			message := fmt.Sprintf("Could not save court: Name: %s", court.Name)
 			f.Errorf(message)
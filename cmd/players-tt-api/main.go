@@ -14,6 +14,7 @@ import (
 	"github.com/rsmaxwell/players-tt-api/internal/cmdline"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/httphandler"
 	"github.com/rsmaxwell/players-tt-api/internal/mqtthandler"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
 	"github.com/rsmaxwell/players-tt-api/internal/utils"
@@ -28,28 +29,107 @@ var (
 	functionConnectLostHandler = debug.NewFunction(pkg, "connectLostHandler")
 	functionMain               = debug.NewFunction(pkg, "main")
 	functionOnMessage          = debug.NewFunction(pkg, "onMessage")
+	functionServeHttp          = debug.NewFunction(pkg, "serveHttp")
 )
 
 const maxDuration time.Duration = 1<<63 - 1
 
 var (
 	handlers = map[string]mqtthandler.Handler{
-		"register":     mqtthandler.Register,
-		"signin":       mqtthandler.Signin,
-		"getCourts":    mqtthandler.GetCourts,
-		"getPeople":    mqtthandler.GetPeople,
-		"getPerson":    mqtthandler.GetPerson,
-		"updatePerson": mqtthandler.UpdatePerson,
-		"getWaiters":   mqtthandler.GetWaiters,
-		"refreshToken": mqtthandler.RefreshToken,
-		"getCourt":     mqtthandler.GetCourt,
-		"updateCourt":  mqtthandler.UpdateCourt,
-		"createCourt":  mqtthandler.CreateCourt,
-		"deleteCourt":  mqtthandler.DeleteCourt,
-		"deletePerson": mqtthandler.DeletePerson,
-		"fillCourt":    mqtthandler.FillCourt,
-		"clearCourt":   mqtthandler.ClearCourt,
-		"updateGame":   mqtthandler.UpdateGame,
+		"register":           mqtthandler.Register,
+		"signin":             mqtthandler.Signin,
+		"getCourts":          mqtthandler.GetCourts,
+		"getPeople":          mqtthandler.GetPeople,
+		"getPerson":          mqtthandler.GetPerson,
+		"updatePerson":       mqtthandler.UpdatePerson,
+		"getWaiters":         mqtthandler.GetWaiters,
+		"refreshToken":       mqtthandler.RefreshToken,
+		"getCourt":           mqtthandler.GetCourt,
+		"updateCourt":        mqtthandler.UpdateCourt,
+		"createCourt":        mqtthandler.CreateCourt,
+		"deleteCourt":        mqtthandler.DeleteCourt,
+		"deletePerson":       mqtthandler.DeletePerson,
+		"fillCourt":          mqtthandler.FillCourt,
+		"fillAllCourts":      mqtthandler.FillAllCourts,
+		"clearCourt":         mqtthandler.ClearCourt,
+		"updateGame":         mqtthandler.UpdateGame,
+		"batch":              mqtthandler.Batch,
+		"republishAll":       mqtthandler.RepublishAll,
+		"getMetrics":         mqtthandler.GetMetrics,
+		"getGames":           mqtthandler.GetGames,
+		"recordScore":        mqtthandler.RecordScore,
+		"recalculateRatings": mqtthandler.RecalculateRatings,
+		"openSession":        mqtthandler.OpenSession,
+		"closeSession":       mqtthandler.CloseSession,
+		"checkIn":            mqtthandler.CheckIn,
+		"checkOut":           mqtthandler.CheckOut,
+		"pauseWaiter":        mqtthandler.PauseWaiter,
+		"resumeWaiter":       mqtthandler.ResumeWaiter,
+		"movePlayer":         mqtthandler.MovePlayer,
+		"swapPlayers":        mqtthandler.SwapPlayers,
+		"moveWaiter":         mqtthandler.MoveWaiter,
+		"setWaiterPriority":  mqtthandler.SetWaiterPriority,
+		"acceptCall":         mqtthandler.AcceptCall,
+		"getNotifications":   mqtthandler.GetNotifications,
+	}
+
+	// Requests with a key in common are run in order, and the others run in parallel. Only the
+	// requests which read or change the waiting list, such as moving people on or off a court, take
+	// the waiting key. The scheduler takes the waiting key and the keys of all the courts
+	orderings = map[string]mqtthandler.KeyFunc{
+		"getPerson":         mqtthandler.PersonKey("id"),
+		"updatePerson":      mqtthandler.PersonKey("id"),
+		"deletePerson":      mqtthandler.Keys(mqtthandler.PersonKey("id"), mqtthandler.Waiting()),
+		"getCourt":          mqtthandler.CourtKey("id"),
+		"updateCourt":       mqtthandler.CourtKey("id"),
+		"deleteCourt":       mqtthandler.Keys(mqtthandler.CourtKey("id"), mqtthandler.Waiting()),
+		"fillCourt":         mqtthandler.Keys(mqtthandler.CourtKey("courtID"), mqtthandler.Waiting()),
+		"clearCourt":        mqtthandler.Keys(mqtthandler.CourtKey("courtID"), mqtthandler.Waiting()),
+		"fillAllCourts":     mqtthandler.Waiting(),
+		"updateGame":        mqtthandler.Keys(mqtthandler.CourtKey("court"), mqtthandler.Waiting()),
+		"batch":             mqtthandler.BatchKeys(),
+		"recordScore":       mqtthandler.CourtKey("court"),
+		"openSession":       mqtthandler.Waiting(),
+		"closeSession":      mqtthandler.Waiting(),
+		"checkIn":           mqtthandler.Waiting(),
+		"checkOut":          mqtthandler.Waiting(),
+		"pauseWaiter":       mqtthandler.Waiting(),
+		"resumeWaiter":      mqtthandler.Waiting(),
+		"movePlayer":        mqtthandler.Keys(mqtthandler.PlaceCourtKey("from"), mqtthandler.PlaceCourtKey("to"), mqtthandler.Waiting()),
+		"swapPlayers":       mqtthandler.Keys(mqtthandler.PlaceCourtKey("from"), mqtthandler.PlaceCourtKey("to"), mqtthandler.Waiting()),
+		"moveWaiter":        mqtthandler.Waiting(),
+		"setWaiterPriority": mqtthandler.Waiting(),
+		"acceptCall":        mqtthandler.Waiting(),
+	}
+
+	// The replies to these commands are replayed when a client repeats an idempotency key. A person
+	// registers before they can sign in, so their keys are scoped by email. Signing in and reading
+	// are left out, as their replies hold tokens or data which must not be kept
+	idempotent = map[string]mqtthandler.ScopeFunc{
+		"register":           mqtthandler.FieldScope("email"),
+		"updatePerson":       mqtthandler.UserScope(),
+		"deletePerson":       mqtthandler.UserScope(),
+		"createCourt":        mqtthandler.UserScope(),
+		"updateCourt":        mqtthandler.UserScope(),
+		"deleteCourt":        mqtthandler.UserScope(),
+		"fillCourt":          mqtthandler.UserScope(),
+		"fillAllCourts":      mqtthandler.UserScope(),
+		"clearCourt":         mqtthandler.UserScope(),
+		"updateGame":         mqtthandler.UserScope(),
+		"batch":              mqtthandler.UserScope(),
+		"recordScore":        mqtthandler.UserScope(),
+		"recalculateRatings": mqtthandler.UserScope(),
+		"openSession":        mqtthandler.UserScope(),
+		"closeSession":       mqtthandler.UserScope(),
+		"checkIn":            mqtthandler.UserScope(),
+		"checkOut":           mqtthandler.UserScope(),
+		"pauseWaiter":        mqtthandler.UserScope(),
+		"resumeWaiter":       mqtthandler.UserScope(),
+		"movePlayer":         mqtthandler.UserScope(),
+		"swapPlayers":        mqtthandler.UserScope(),
+		"moveWaiter":         mqtthandler.UserScope(),
+		"setWaiterPriority":  mqtthandler.UserScope(),
+		"acceptCall":         mqtthandler.UserScope(),
 	}
 )
 
@@ -58,9 +138,19 @@ var messagePubHandler mqtt.MessageHandler = func(client mqtt.Client, msg mqtt.Me
 	f.DebugVerbose("Received message: %s from topic: %s\n", msg.Payload(), msg.Topic())
 }
 
+// connectHandler is called on the first connection and after every reconnection. The broker may have
+// restarted and lost its subscriptions and retained messages, so both are set up again
 var connectHandler mqtt.OnConnectHandler = func(client mqtt.Client) {
 	f := functionConnectHandler
 	f.DebugVerbose("Connected")
+
+	utils.Subscribe(client, "request", onMessage)
+
+	err := publisher.RepublishAll(db, cfg)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		f.DumpError(err, "Could not republish")
+	}
 }
 
 var connectLostHandler mqtt.ConnectionLostHandler = func(client mqtt.Client, err error) {
@@ -72,7 +162,7 @@ var connectLostHandler mqtt.ConnectionLostHandler = func(client mqtt.Client, err
 
 var db *sql.DB
 var cfg *config.Config
-var requestID = 0
+var dispatcher *mqtthandler.Dispatcher
 
 func main() {
 	f := functionMain
@@ -135,6 +225,11 @@ func main() {
 	opts.OnConnectionLost = connectLostHandler
 	client := mqtt.NewClient(opts)
 
+	publisher.SetClient(client)
+
+	dispatcher = mqtthandler.NewDispatcher(db, cfg, handlers, orderings, idempotent)
+	dispatcher.StartScheduler(cfg.SchedulerInterval)
+
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
 		err := token.Error()
 		f.DebugVerbose(err.Error())
@@ -142,21 +237,27 @@ func main() {
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
-	if err != nil {
-		f.DebugVerbose(err.Error())
-		f.DumpError(err, "Could not update publications")
-		return
+	if cfg.Server.Port > 0 {
+		go serveHttp()
 	}
 
-	utils.Subscribe(client, "request", onMessage)
 	time.Sleep(maxDuration)
 }
 
+func serveHttp() {
+	f := functionServeHttp
+
+	err := httphandler.Serve(cfg, dispatcher)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		f.DumpError(err, "Could not serve http on port %d", cfg.Server.Port)
+	}
+}
+
 var onMessage mqtt.MessageHandler = func(client mqtt.Client, message mqtt.Message) {
 	f := functionOnMessage
 
-	requestID = requestID + 1
+	requestID := mqtthandler.NextRequestID()
 	mqtthandler.DebugVerbose(f, requestID, "------------------------------------------------------------------------------------------------")
 
 	payload := message.Payload()
@@ -175,28 +276,41 @@ var onMessage mqtt.MessageHandler = func(client mqtt.Client, message mqtt.Messag
 		return
 	}
 
-	command, err := mqtthandler.GetCommand(requestID, request)
+	correlationID, err := mqtthandler.GetOptionalStringField(requestID, request, "correlationId")
 	if err != nil {
-		message := fmt.Sprintf("Unexpected 'command' in request: %s", err.Error())
-		mqtthandler.DebugVerbose(f, requestID, message)
-		mqtthandler.ReplyBadRequest(requestID, client, replyTopic, message)
+		mqtthandler.DebugVerbose(f, requestID, "Unexpected 'correlationId' in request: %s", err.Error())
 		return
 	}
 
-	handler := handlers[command]
-	if handler == nil {
-		message := fmt.Sprintf("Command not found: %s", command)
+	responder := mqtthandler.NewCorrelatedResponder(mqtthandler.NewMqttResponder(client, replyTopic), correlationID)
+
+	command, err := mqtthandler.GetCommand(requestID, request)
+	if err != nil {
+		message := fmt.Sprintf("Unexpected 'command' in request: %s", err.Error())
 		mqtthandler.DebugVerbose(f, requestID, message)
-		mqtthandler.ReplyBadRequest(requestID, client, replyTopic, message)
+		mqtthandler.ReplyBadRequest(requestID, responder, message)
 		return
 	}
 
 	data, err := mqtthandler.GetData(request)
 	if err != nil {
 		f.DebugVerbose("Unexpected 'data' in request: %s", err.Error())
-		mqtthandler.ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		mqtthandler.ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
-	handler(db, cfg, requestID, client, replyTopic, data)
+	idempotencyKey, err := mqtthandler.GetOptionalStringField(requestID, request, "idempotencyKey")
+	if err != nil {
+		message := fmt.Sprintf("Unexpected 'idempotencyKey' in request: %s", err.Error())
+		mqtthandler.DebugVerbose(f, requestID, message)
+		mqtthandler.ReplyBadRequest(requestID, responder, message)
+		return
+	}
+
+	_, ok := dispatcher.Dispatch(requestID, command, idempotencyKey, responder, data)
+	if !ok {
+		message := fmt.Sprintf("Command not found: %s", command)
+		mqtthandler.DebugVerbose(f, requestID, message)
+		mqtthandler.ReplyBadRequest(requestID, responder, message)
+	}
 }
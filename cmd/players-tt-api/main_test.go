@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/mqtthandler"
+)
+
+func TestOrderingsParallel(t *testing.T) {
+
+	cases := []struct {
+		command string
+		first   map[string]interface{}
+		second  map[string]interface{}
+	}{
+		{"updateCourt", map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}},
+		{"recordScore", map[string]interface{}{"court": 1.0}, map[string]interface{}{"court": 2.0}},
+		{"updatePerson", map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}},
+	}
+
+	pool := mqtthandler.NewPool(2, 10)
+
+	for _, c := range cases {
+		keyFunc := orderings[c.command]
+		firstKeys := keyFunc(&c.first)
+		secondKeys := keyFunc(&c.second)
+
+		// the first request only finishes once the second has started
+		started := make(chan struct{})
+		parallel := make(chan bool)
+
+		pool.Submit(firstKeys, func() {
+			select {
+			case <-started:
+				parallel <- true
+			case <-time.After(time.Second):
+				parallel <- false
+			}
+		})
+		pool.Submit(secondKeys, func() {
+			close(started)
+		})
+
+		if !<-parallel {
+			t.Logf("The '%s' requests %v and %v did not run in parallel", c.command, firstKeys, secondKeys)
+			t.FailNow()
+		}
+	}
+}
@@ -18,6 +18,11 @@ func (e CodeError) Error() string {
 	return e.message
 }
 
+// Status function
+func (e CodeError) Status() int {
+	return e.status
+}
+
 // Code function
 func (e CodeError) Code() string {
 	return e.code
@@ -62,3 +67,8 @@ func NewUnauthorized(message string) *CodeError {
 func NewUnauthorizedJWTExpired(message string) *CodeError {
 	return &CodeError{message: message, status: http.StatusUnauthorized}
 }
+
+// NewConflict function
+func NewConflict(message string) *CodeError {
+	return &CodeError{message: message, status: http.StatusConflict}
+}
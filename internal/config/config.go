@@ -39,14 +39,54 @@ type Mqtt struct {
 	Password string `json:"password"`
 }
 
+// Requests type
+type Requests struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queueSize"`
+}
+
+// Publisher type
+type Publisher struct {
+	Patches []string `json:"patches"`
+}
+
+// Scoring type
+type Scoring struct {
+	BestOf int `json:"bestOf"`
+}
+
+// Filling type
+type Filling struct {
+	Strategy string `json:"strategy"`
+}
+
+// Rotation type. The action is what happens to a court when its game runs out of time
+type Rotation struct {
+	Action string `json:"action"`
+}
+
+// Calls type. NoShow is what happens to a person who does not accept their call to a court in time
+type Calls struct {
+	NoShow string `json:"noShow"`
+}
+
 // Config type
 type ConfigFile struct {
-	Database           Database `json:"database"`
-	Server             Server   `json:"server"`
-	Mqtt               Mqtt     `json:"mqtt"`
-	AccessTokenExpiry  string   `json:"accessToken_expiry"`
-	RefreshTokenExpiry string   `json:"refreshToken_expiry"`
-	ClientRefreshDelta string   `json:"clientRefreshDelta"`
+	Database           Database  `json:"database"`
+	Server             Server    `json:"server"`
+	Mqtt               Mqtt      `json:"mqtt"`
+	Requests           Requests  `json:"requests"`
+	Publisher          Publisher `json:"publisher"`
+	Scoring            Scoring   `json:"scoring"`
+	Filling            Filling   `json:"filling"`
+	Rotation           Rotation  `json:"rotation"`
+	Calls              Calls     `json:"calls"`
+	AccessTokenExpiry  string    `json:"accessToken_expiry"`
+	RefreshTokenExpiry string    `json:"refreshToken_expiry"`
+	ClientRefreshDelta string    `json:"clientRefreshDelta"`
+	IdempotencyWindow  string    `json:"idempotencyWindow"`
+	SchedulerInterval  string    `json:"schedulerInterval"`
+	CallTimeout        string    `json:"callTimeout"`
 }
 
 // Config type
@@ -54,9 +94,18 @@ type Config struct {
 	Database           Database
 	Server             Server
 	Mqtt               Mqtt
+	Requests           Requests
+	Publisher          Publisher
+	Scoring            Scoring
+	Filling            Filling
+	Rotation           Rotation
+	Calls              Calls
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
 	ClientRefreshDelta time.Duration
+	IdempotencyWindow  time.Duration
+	SchedulerInterval  time.Duration
+	CallTimeout        time.Duration
 }
 
 var (
@@ -66,6 +115,22 @@ var (
 
 const (
 	DefaultConfigFile = "config.json"
+
+	DefaultWorkers          = 8
+	DefaultRequestQueueSize = 100
+	DefaultBestOf           = 5
+
+	// RotationClear sends the players of a game which runs out of time back to the waiting list
+	RotationClear = "clear"
+
+	// RotationRefill also fills the court again from the waiting list
+	RotationRefill = "refill"
+
+	// NoShowKeepPlace sends a person who does not accept their call back to their place in the queue, paused
+	NoShowKeepPlace = "keepPlace"
+
+	// NoShowMoveToBack sends a person who does not accept their call to the back of the queue
+	NoShowMoveToBack = "moveToBack"
 )
 
 // Open returns the configuration
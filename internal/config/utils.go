@@ -13,7 +13,29 @@ var (
 )
 
 func (c *ConfigFile) toConfig() (*Config, error) {
-	config := Config{Database: c.Database, Server: c.Server, Mqtt: c.Mqtt}
+	config := Config{Database: c.Database, Server: c.Server, Mqtt: c.Mqtt, Requests: c.Requests, Publisher: c.Publisher, Scoring: c.Scoring, Filling: c.Filling, Rotation: c.Rotation, Calls: c.Calls}
+
+	if config.Requests.Workers <= 0 {
+		config.Requests.Workers = DefaultWorkers
+	}
+	if config.Requests.QueueSize <= 0 {
+		config.Requests.QueueSize = DefaultRequestQueueSize
+	}
+	if config.Scoring.BestOf <= 0 {
+		config.Scoring.BestOf = DefaultBestOf
+	}
+	if config.Rotation.Action == "" {
+		config.Rotation.Action = RotationClear
+	}
+	if config.Rotation.Action != RotationClear && config.Rotation.Action != RotationRefill {
+		return nil, fmt.Errorf("unexpected rotation action: '%s', expected '%s' or '%s'", config.Rotation.Action, RotationClear, RotationRefill)
+	}
+	if config.Calls.NoShow == "" {
+		config.Calls.NoShow = NoShowKeepPlace
+	}
+	if config.Calls.NoShow != NoShowKeepPlace && config.Calls.NoShow != NoShowMoveToBack {
+		return nil, fmt.Errorf("unexpected no-show policy: '%s', expected '%s' or '%s'", config.Calls.NoShow, NoShowKeepPlace, NoShowMoveToBack)
+	}
 
 	var err error
 	config.AccessTokenExpiry, err = GetDuration("AccessTokenExpiry", c.AccessTokenExpiry, "10m")
@@ -31,6 +53,22 @@ func (c *ConfigFile) toConfig() (*Config, error) {
 		return nil, err
 	}
 
+	config.IdempotencyWindow, err = GetDuration("IdempotencyWindow", c.IdempotencyWindow, "10m")
+	if err != nil {
+		return nil, err
+	}
+
+	config.SchedulerInterval, err = GetDuration("SchedulerInterval", c.SchedulerInterval, "5s")
+	if err != nil {
+		return nil, err
+	}
+
+	// calls do not run out unless a timeout is configured
+	config.CallTimeout, err = GetDuration("CallTimeout", c.CallTimeout, "0s")
+	if err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -0,0 +1,172 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rsmaxwell/players-tt-api/internal/basic"
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/mqtthandler"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+)
+
+var (
+	functionBrokerUserFunc      = debug.NewFunction(pkg, "BrokerUserFunc")
+	functionBrokerSuperuserFunc = debug.NewFunction(pkg, "BrokerSuperuserFunc")
+	functionBrokerAclFunc       = debug.NewFunction(pkg, "BrokerAclFunc")
+)
+
+// The broker asks these paths whether a client may connect and use a topic, in the way of the http
+// backend of the mosquitto-go-auth plugin. The answer is the status: 200 allows, anything else refuses
+const (
+	brokerUserPath      = "/mqtt/user"
+	brokerSuperuserPath = "/mqtt/superuser"
+	brokerAclPath       = "/mqtt/acl"
+
+	// the access asked for on a topic
+	brokerRead      = 1
+	brokerWrite     = 2
+	brokerReadWrite = 3
+	brokerSubscribe = 4
+
+	// clients publish their requests on the request topic, and get the replies on 'reply/{clientid}'
+	brokerRequestTopic = "request"
+)
+
+// brokerRequest is the json body the broker sends. Only the fields of the check are filled in
+type brokerRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ClientID string `json:"clientid"`
+	Topic    string `json:"topic"`
+	Acc      int    `json:"acc"`
+}
+
+// BrokerUserFunc returns the handler which checks a client connecting to the broker. A person connects
+// with their id as the username and their access token as the password, so the broker knows them by
+// the identity in the token. A client without a username connects anonymously, and the server connects
+// with the configured credentials
+func BrokerUserFunc(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := functionBrokerUserFunc
+
+		request, ok := readBrokerRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if request.Username == "" || isServer(cfg, request.Username, request.Password) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		claims, err := basic.ValidateToken(request.Password)
+		if err != nil {
+			f.DebugVerbose("client [%s] refused: %s", request.ClientID, err.Error())
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if request.Username != strconv.Itoa(claims.ID) {
+			message := fmt.Sprintf("the username [%s] is not the user of the token", request.Username)
+			f.DebugVerbose("client [%s] refused: %s", request.ClientID, message)
+			http.Error(w, message, http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// BrokerSuperuserFunc returns the handler which reports whether a client is the server, which may use
+// every topic
+func BrokerSuperuserFunc(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := functionBrokerSuperuserFunc
+
+		request, ok := readBrokerRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if cfg.Mqtt.Username == "" || request.Username != cfg.Mqtt.Username {
+			f.DebugVerbose("[%s] is not a superuser", request.Username)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// BrokerAclFunc returns the handler which checks a client's access to a topic. The server is a
+// superuser, so it is not asked about here
+func BrokerAclFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := functionBrokerAclFunc
+
+		request, ok := readBrokerRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if !brokerAllows(request.Username, request.ClientID, request.Topic, request.Acc) {
+			f.DebugVerbose("[%s] refused access %d to [%s]", request.Username, request.Acc, request.Topic)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// brokerAllows reports whether a client, known by the username and client id it connected with, has
+// the access to the topic. A client may only publish its requests, which carry access tokens so are
+// not readable, and may use its own reply topic. Otherwise a client reads the topics it can read
+// through the server, and never publishes on them
+func brokerAllows(username string, clientID string, topic string, acc int) bool {
+
+	levels := strings.Split(topic, "/")
+	if levels[0] == mqtthandler.ReplyTopicPrefix {
+		return clientID != "" && len(levels) == 2 && levels[1] == clientID
+	}
+
+	if topic == brokerRequestTopic {
+		return acc == brokerWrite
+	}
+
+	if acc != brokerRead && acc != brokerSubscribe {
+		return false
+	}
+
+	// a client which is not a person reads as someone who has not signed in
+	personID, _ := strconv.Atoi(username)
+
+	return publisher.CanRead(personID, topic)
+}
+
+// isServer reports whether the credentials are the ones the server connects to the broker with
+func isServer(cfg *config.Config, username string, password string) bool {
+	return cfg.Mqtt.Username != "" && username == cfg.Mqtt.Username && password == cfg.Mqtt.Password
+}
+
+func readBrokerRequest(w http.ResponseWriter, r *http.Request) (*brokerRequest, bool) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("Method not allowed: %s", r.Method), http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	request := new(brokerRequest)
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("the body is not a json object: %s", err.Error()), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return request, true
+}
@@ -0,0 +1,42 @@
+package httphandler
+
+import (
+	"testing"
+)
+
+func TestBrokerAllows(t *testing.T) {
+
+	cases := []struct {
+		username string
+		clientID string
+		topic    string
+		acc      int
+		allowed  bool
+	}{
+		{"", "c1", "getCourts", brokerRead, true},
+		{"", "c1", "getCourts", brokerSubscribe, true},
+		{"", "c1", "getCourts", brokerWrite, false},
+		{"5", "c1", "getCourts", brokerReadWrite, false},
+		{"5", "c1", "getCourt/1/patch", brokerWrite, false},
+		{"5", "c1", "request", brokerWrite, true},
+		{"5", "c1", "request", brokerRead, false},
+		{"5", "c1", "request", brokerSubscribe, false},
+		{"5", "c1", "reply/c1", brokerSubscribe, true},
+		{"5", "c1", "reply/c1", brokerWrite, true},
+		{"5", "c1", "reply/c2", brokerRead, false},
+		{"5", "c1", "reply/+", brokerSubscribe, false},
+		{"5", "", "reply/", brokerSubscribe, false},
+		{"5", "c1", "user/5/notifications", brokerSubscribe, true},
+		{"5", "c1", "user/5/notifications", brokerWrite, false},
+		{"6", "c1", "user/5/notifications", brokerRead, false},
+		{"", "c1", "user/5/call", brokerRead, false},
+	}
+
+	for _, c := range cases {
+		allowed := brokerAllows(c.username, c.clientID, c.topic, c.acc)
+		if allowed != c.allowed {
+			t.Logf("Unexpected access %d for [%s] client [%s] to '%s': expected %t, got %t", c.acc, c.username, c.clientID, c.topic, c.allowed, allowed)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/mqtthandler"
+)
+
+var (
+	pkg                 = debug.NewPackage("httphandler")
+	functionServe       = debug.NewFunction(pkg, "Serve")
+	functionHandlerFunc = debug.NewFunction(pkg, "HandlerFunc")
+)
+
+const (
+	apiPrefix = "/api/"
+
+	correlationIDHeader  = "X-Correlation-Id"
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// Serve listens on the configured server port and dispatches each 'POST /api/{command}' to the same
+// handlers as the mqtt 'request' topic. The publications are streamed on '/stream', the metrics
+// are served on '/metrics', and the broker checks its clients on '/mqtt/...'
+func Serve(cfg *config.Config, dispatcher *mqtthandler.Dispatcher) error {
+	f := functionServe
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix, HandlerFunc(dispatcher))
+	mux.HandleFunc(streamPath, StreamFunc())
+	mux.HandleFunc(metricsPath, MetricsFunc())
+	mux.HandleFunc(brokerUserPath, BrokerUserFunc(cfg))
+	mux.HandleFunc(brokerSuperuserPath, BrokerSuperuserFunc(cfg))
+	mux.HandleFunc(brokerAclPath, BrokerAclFunc())
+
+	address := fmt.Sprintf(":%d", cfg.Server.Port)
+	f.DebugInfo("Listening on %s", address)
+
+	return http.ListenAndServe(address, mux)
+}
+
+// HandlerFunc returns the http handler for the api
+func HandlerFunc(dispatcher *mqtthandler.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := functionHandlerFunc
+
+		requestID := mqtthandler.NextRequestID()
+		mqtthandler.DebugVerbose(f, requestID, "------------------------------------------------------------------------------------------------")
+		mqtthandler.DebugVerbose(f, requestID, "%s %s", r.Method, r.URL.Path)
+
+		httpResponder := NewHttpResponder(w)
+		defer httpResponder.Finish(requestID)
+
+		correlationID := r.Header.Get(correlationIDHeader)
+		if correlationID != "" {
+			w.Header().Set(correlationIDHeader, correlationID)
+		}
+		responder := mqtthandler.NewCorrelatedResponder(httpResponder, correlationID)
+
+		if r.Method != http.MethodPost {
+			mqtthandler.ReplyStatus(requestID, responder, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+			return
+		}
+
+		command := strings.TrimPrefix(r.URL.Path, apiPrefix)
+
+		data, err := readData(r)
+		if err != nil {
+			mqtthandler.DebugVerbose(f, requestID, "Unexpected body: %s", err.Error())
+			mqtthandler.ReplyBadRequest(requestID, responder, err.Error())
+			return
+		}
+
+		accessToken, ok := getBearerToken(r)
+		if ok {
+			data["accessToken"] = accessToken
+		}
+
+		idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+
+		done, ok := dispatcher.Dispatch(requestID, command, idempotencyKey, responder, &data)
+		if !ok {
+			mqtthandler.ReplyStatus(requestID, responder, http.StatusNotFound, fmt.Sprintf("Command not found: %s", command))
+			return
+		}
+		<-done
+	}
+}
+
+// readData reads the request body, which holds the same 'data' object as an mqtt request
+func readData(r *http.Request) (map[string]interface{}, error) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return data, nil
+	}
+
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("the body is not a json object: %s", err.Error())
+	}
+
+	return data, nil
+}
+
+// getBearerToken returns the token from an 'Authorization: Bearer <token>' header
+func getBearerToken(r *http.Request) (string, bool) {
+
+	header := r.Header.Get("Authorization")
+	prefix := "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(header, prefix)), true
+}
@@ -0,0 +1,85 @@
+package httphandler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+const (
+	metricsPath = "/metrics"
+)
+
+// MetricsFunc returns the http handler for 'GET /metrics', which writes the metrics in the
+// Prometheus text format
+func MetricsFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("Method not allowed: %s", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, model.MetricsData.Snapshot())
+	}
+}
+
+func writeMetrics(w io.Writer, metrics *model.Metrics) {
+
+	commands := []string{}
+	for command := range metrics.Commands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	fmt.Fprintln(w, "# HELP players_requests_total Requests handled, by command and reply status.")
+	fmt.Fprintln(w, "# TYPE players_requests_total counter")
+	for _, command := range commands {
+		c := metrics.Commands[command]
+		for _, status := range sortedStatuses(c.StatusCodes) {
+			fmt.Fprintf(w, "players_requests_total{command=%q,status=\"%d\"} %d\n", command, status, c.StatusCodes[status])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP players_request_duration_seconds Time from the arrival of a request to its reply.")
+	fmt.Fprintln(w, "# TYPE players_request_duration_seconds histogram")
+	for _, command := range commands {
+		h := metrics.Commands[command].Latency
+		cumulative := 0
+		for i, bound := range h.Buckets {
+			cumulative += h.Counts[i]
+			fmt.Fprintf(w, "players_request_duration_seconds_bucket{command=%q,le=%q} %d\n", command, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "players_request_duration_seconds_bucket{command=%q,le=\"+Inf\"} %d\n", command, h.Count)
+		fmt.Fprintf(w, "players_request_duration_seconds_sum{command=%q} %g\n", command, h.Sum)
+		fmt.Fprintf(w, "players_request_duration_seconds_count{command=%q} %d\n", command, h.Count)
+	}
+
+	fmt.Fprintln(w, "# HELP players_replies_total Replies sent, by status.")
+	fmt.Fprintln(w, "# TYPE players_replies_total counter")
+	for _, status := range sortedStatuses(metrics.StatusCodes) {
+		fmt.Fprintf(w, "players_replies_total{status=\"%d\"} %d\n", status, metrics.StatusCodes[status])
+	}
+
+	fmt.Fprintln(w, "# HELP players_publications_total Messages sent by the publisher.")
+	fmt.Fprintln(w, "# TYPE players_publications_total counter")
+	fmt.Fprintf(w, "players_publications_total %d\n", metrics.Publications)
+
+	fmt.Fprintln(w, "# HELP players_database_errors_total Transactions which failed with a database error.")
+	fmt.Fprintln(w, "# TYPE players_database_errors_total counter")
+	fmt.Fprintf(w, "players_database_errors_total %d\n", metrics.DatabaseErrors)
+}
+
+func sortedStatuses(statusCodes map[int]int) []int {
+	statuses := []int{}
+	for status := range statusCodes {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	return statuses
+}
@@ -0,0 +1,71 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/mqtthandler"
+)
+
+var (
+	functionRespond = debug.NewFunction(pkg, "Respond")
+)
+
+// HttpResponder writes the reply to a request as the body of the http response, using the
+// 'status' field of the reply as the http status code
+type HttpResponder struct {
+	w       http.ResponseWriter
+	replied bool
+}
+
+// NewHttpResponder function
+func NewHttpResponder(w http.ResponseWriter) *HttpResponder {
+	return &HttpResponder{w: w}
+}
+
+// Respond method
+func (r *HttpResponder) Respond(requestID int, object interface{}) {
+	f := functionRespond
+
+	if r.replied {
+		mqtthandler.DebugVerbose(f, requestID, "Ignoring a second reply: %#v", object)
+		return
+	}
+	r.replied = true
+
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		mqtthandler.DumpError(f, err, requestID, "Could not marshal the reply")
+		r.w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	reply := struct {
+		Status int `json:"status"`
+	}{}
+	json.Unmarshal(bytes, &reply)
+
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	mqtthandler.DebugVerbose(f, requestID, "Reply [%d] message: %s", status, string(bytes))
+
+	r.w.Header().Set("Content-Type", "application/json")
+	r.w.WriteHeader(status)
+	r.w.Write(bytes)
+}
+
+// Error replies with a status and message, in the same form as the mqtt replies
+func (r *HttpResponder) Error(requestID int, status int, message string) {
+	mqtthandler.ReplyStatus(requestID, r, status, message)
+}
+
+// Finish makes sure there is a response, even when a handler returned without replying
+func (r *HttpResponder) Finish(requestID int) {
+	if !r.replied {
+		r.Error(requestID, http.StatusInternalServerError, "no reply")
+	}
+}
@@ -0,0 +1,124 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/basic"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+)
+
+var (
+	functionStreamFunc = debug.NewFunction(pkg, "StreamFunc")
+)
+
+const (
+	streamPath        = "/stream"
+	keepAliveInterval = 30 * time.Second
+)
+
+// StreamFunc returns the http handler for 'GET /stream?topic=...', which sends the publications as
+// Server-Sent Events. Each 'topic' parameter is a filter using the mqtt wildcards, and the default
+// is every topic. The current publications are sent first, followed by every later change. The
+// user's private topics are only sent with their access token, as a bearer token or as the
+// 'accessToken' parameter for clients which cannot set headers
+func StreamFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := functionStreamFunc
+
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("Method not allowed: %s", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		filters := r.URL.Query()["topic"]
+		if len(filters) == 0 {
+			filters = []string{"#"}
+		}
+
+		userID := 0
+		accessToken, ok := getBearerToken(r)
+		if !ok {
+			accessToken = r.URL.Query().Get("accessToken")
+		}
+		if accessToken != "" {
+			claims, err := basic.ValidateToken(accessToken)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			userID = claims.ID
+		}
+
+		f.DebugVerbose("Stream opened for %v by user [%d]", filters, userID)
+
+		listener, snapshot := publisher.Listen(filters, userID)
+		defer listener.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for _, message := range snapshot {
+			err := writeEvent(w, message)
+			if err != nil {
+				f.DebugVerbose(err.Error())
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				f.DebugVerbose("Stream closed by the client")
+				return
+
+			case message, ok := <-listener.C:
+				if !ok {
+					f.DebugVerbose("Stream closed because the client fell behind")
+					return
+				}
+				err := writeEvent(w, message)
+				if err != nil {
+					f.DebugVerbose(err.Error())
+					return
+				}
+				flusher.Flush()
+
+			case <-ticker.C:
+				_, err := fmt.Fprint(w, ": keep-alive\n\n")
+				if err != nil {
+					f.DebugVerbose(err.Error())
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeEvent writes a publication as a single event, with the topic and message as a json object
+func writeEvent(w http.ResponseWriter, message publisher.Message) error {
+
+	bytes, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "data: %s\n\n", string(bytes))
+	return err
+}
@@ -0,0 +1,43 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionAcceptCall = debug.NewFunction(pkg, "AcceptCall")
+)
+
+// AcceptCall method confirms the user has heard their call to a court and is coming to play
+func AcceptCall(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionAcceptCall
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	err = model.AcceptCall(db, userID)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	ReplyOK(requestID, responder)
+}
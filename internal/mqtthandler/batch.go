@@ -0,0 +1,262 @@
+package mqtthandler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionBatch           = debug.NewFunction(pkg, "Batch")
+	functionParseBatchSteps = debug.NewFunction(pkg, "parseBatchSteps")
+)
+
+// batchOperation runs one sub-command of a batch inside the batch's transaction, and returns its reply
+type batchOperation func(ctx context.Context, db model.Querier, cfg *config.Config, requestID int, user *model.FullPerson, data *map[string]interface{}) (interface{}, error)
+
+var (
+	batchOperations = map[string]batchOperation{
+		"fillCourt":  batchFillCourt,
+		"clearCourt": batchClearCourt,
+		"updateGame": batchUpdateGame,
+	}
+)
+
+type batchStep struct {
+	Command string
+	Data    *map[string]interface{}
+}
+
+// Batch method runs a list of sub-commands in a single transaction. Either every sub-command is
+// applied, or none are, and the publications are updated once at the end
+func Batch(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionBatch
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	steps, err := parseBatchSteps(requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	replies := make([]interface{}, len(steps))
+	failed := -1
+
+	err = model.RunInTransaction(db, func(ctx context.Context, tx model.Querier) error {
+		for i, step := range steps {
+			DebugVerbose(f, requestID, "step[%d]: %s", i, step.Command)
+
+			operation := batchOperations[step.Command]
+			reply, err := operation(ctx, tx, cfg, requestID, &user, step.Data)
+			if err != nil {
+				failed = i
+				replies[i] = statusReply(statusOf(err), err.Error())
+				return err
+			}
+			replies[i] = reply
+		}
+		return nil
+	})
+
+	if err != nil {
+		message := fmt.Sprintf("batch failed, nothing was changed: %s", err.Error())
+		DebugVerbose(f, requestID, message)
+
+		status := StatusInternalServerError
+		if failed >= 0 {
+			status = statusOf(err)
+			message = fmt.Sprintf("step %d (%s) failed, nothing was changed: %s", failed, steps[failed].Command, err.Error())
+		}
+
+		for i := range steps {
+			if i < failed || failed < 0 {
+				replies[i] = statusReply(StatusFailedDependency, "rolled back")
+			} else if i > failed {
+				replies[i] = statusReply(StatusFailedDependency, "not run")
+			}
+		}
+
+		replyBatch(requestID, responder, status, message, replies)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	replyBatch(requestID, responder, StatusOK, "ok", replies)
+}
+
+func replyBatch(requestID int, responder Responder, status int, message string, replies []interface{}) {
+
+	reply := struct {
+		Status  int           `json:"status"`
+		Message string        `json:"message"`
+		Replies []interface{} `json:"replies"`
+	}{
+		Status:  status,
+		Message: message,
+		Replies: replies,
+	}
+
+	Reply(requestID, responder, reply)
+}
+
+// parseBatchSteps reads the 'commands' list. Each step is an object holding a 'command' and its 'data'
+func parseBatchSteps(requestID int, data *map[string]interface{}) ([]batchStep, error) {
+	f := functionParseBatchSteps
+	DebugVerbose(f, requestID, "")
+
+	x, ok := (*data)["commands"]
+	if !ok {
+		return nil, fmt.Errorf("missing field: 'commands'")
+	}
+
+	list, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for 'commands': %#v", x)
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("the batch has no commands")
+	}
+
+	steps := []batchStep{}
+	for i, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected type for commands[%d]: %#v", i, item)
+		}
+
+		command, ok := object["command"].(string)
+		if !ok {
+			return nil, fmt.Errorf("missing or unexpected 'command' in commands[%d]", i)
+		}
+
+		_, ok = batchOperations[command]
+		if !ok {
+			return nil, fmt.Errorf("command not allowed in a batch: commands[%d]: %s", i, command)
+		}
+
+		stepData := map[string]interface{}{}
+		if y, found := object["data"]; found {
+			stepData, ok = y.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected type for 'data' in commands[%d]: %#v", i, y)
+			}
+		}
+
+		steps = append(steps, batchStep{Command: command, Data: &stepData})
+	}
+
+	return steps, nil
+}
+
+func batchFillCourt(ctx context.Context, db model.Querier, cfg *config.Config, requestID int, user *model.FullPerson, data *map[string]interface{}) (interface{}, error) {
+	f := functionBatch
+
+	courtID, err := GetIntegerFromRequest(f, requestID, "courtID", data)
+	if err != nil {
+		return nil, codeerror.NewBadRequest(err.Error())
+	}
+
+	strategy, err := getFillStrategy(f, requestID, cfg, data)
+	if err != nil {
+		return nil, codeerror.NewBadRequest(err.Error())
+	}
+
+	positions, selections, err := model.FillCourtTx(ctx, db, courtID, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	return fillCourtReply(positions, selections), nil
+}
+
+func batchClearCourt(ctx context.Context, db model.Querier, cfg *config.Config, requestID int, user *model.FullPerson, data *map[string]interface{}) (interface{}, error) {
+	f := functionBatch
+
+	courtID, err := GetIntegerFromRequest(f, requestID, "courtID", data)
+	if err != nil {
+		return nil, codeerror.NewBadRequest(err.Error())
+	}
+
+	err = model.ClearCourtTx(ctx, db, courtID)
+	if err != nil {
+		return nil, err
+	}
+
+	return statusReply(StatusOK, "ok"), nil
+}
+
+func batchUpdateGame(ctx context.Context, db model.Querier, cfg *config.Config, requestID int, user *model.FullPerson, data *map[string]interface{}) (interface{}, error) {
+
+	err := user.CanEditGame()
+	if err != nil {
+		return nil, codeerror.NewForbidden(fmt.Sprintf("Person [%d] is not allowed to edit game", user.ID))
+	}
+
+	gameData, err := parseGameData(requestID, data)
+	if err != nil {
+		return nil, codeerror.NewBadRequest(err.Error())
+	}
+
+	err = model.UpdateGameTx(ctx, db, gameData)
+	if err != nil {
+		return nil, err
+	}
+
+	return statusReply(StatusOK, "ok"), nil
+}
+
+// BatchKeys orders a batch against every court its sub-commands touch, and the waiting list
+func BatchKeys() KeyFunc {
+	courtKeys := map[string]KeyFunc{
+		"fillCourt":  CourtKey("courtID"),
+		"clearCourt": CourtKey("courtID"),
+		"updateGame": CourtKey("court"),
+	}
+
+	return func(data *map[string]interface{}) []string {
+		keys := []string{WaitingKey}
+
+		list, _ := (*data)["commands"].([]interface{})
+		for _, item := range list {
+			object, _ := item.(map[string]interface{})
+			command, _ := object["command"].(string)
+			stepData, _ := object["data"].(map[string]interface{})
+
+			courtKey, ok := courtKeys[command]
+			if ok && stepData != nil {
+				keys = append(keys, courtKey(&stepData)...)
+			}
+		}
+
+		return keys
+	}
+}
@@ -0,0 +1,18 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionCheckIn = debug.NewFunction(pkg, "CheckIn")
+)
+
+// CheckIn method puts a person at the back of the waiting list. The person is the user, unless an admin names someone else with 'id'
+func CheckIn(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	selfOrAdmin(functionCheckIn, db, cfg, requestID, responder, data, "check in", model.MakePersonPlayer)
+}
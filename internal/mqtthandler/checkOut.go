@@ -0,0 +1,18 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionCheckOut = debug.NewFunction(pkg, "CheckOut")
+)
+
+// CheckOut method takes a person off their court or out of the waiting list. The person is the user, unless an admin names someone else with 'id'
+func CheckOut(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	selfOrAdmin(functionCheckOut, db, cfg, requestID, responder, data, "check out", model.MakePersonInactive)
+}
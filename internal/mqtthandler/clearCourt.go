@@ -1,11 +1,10 @@
 package mqtthandler
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -16,40 +15,60 @@ var (
 	functionClearCourt = debug.NewFunction(pkg, "ClearCourt")
 )
 
-// ClearCourt method
-func ClearCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+// ClearCourt method. With 'dryRun' the court is not cleared, and the reply shows the result
+func ClearCourt(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionClearCourt
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	courtID, err := GetIntegerFromRequest(f, requestID, "courtID", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	dryRun, err := getDryRun(f, requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
 	DebugVerbose(f, requestID, "courtID: %d", courtID)
 
+	if dryRun {
+		preview, err := model.DryRun(db, courtID, func(ctx context.Context, tx model.Querier) error {
+			return model.ClearCourtTx(ctx, tx, courtID)
+		})
+		if err != nil {
+			DebugVerbose(f, requestID, err.Error())
+			ReplyError(requestID, responder, err)
+			return
+		}
+
+		Reply(requestID, responder, dryRunReply(preview, nil))
+		return
+	}
+
 	err = model.ClearCourt(db, courtID)
 	if err != nil {
 		message := "problem clearing court"
 		d := Dump(f, requestID, message)
 		d.AddString("courtID", fmt.Sprintf("%d", courtID))
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyError(requestID, responder, err)
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		message := err.Error()
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
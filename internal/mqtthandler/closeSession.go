@@ -0,0 +1,71 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionCloseSession = debug.NewFunction(pkg, "CloseSession")
+)
+
+// CloseSession method ends the open session, which empties the courts and the waiting list
+func CloseSession(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionCloseSession
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanManageSession()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to close a session", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	session, err := model.CloseSession(db)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int            `json:"status"`
+		Message string         `json:"message"`
+		Session *model.Session `json:"session"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Session: session,
+	}
+
+	Reply(requestID, responder, reply)
+}
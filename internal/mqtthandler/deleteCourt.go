@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -17,19 +15,19 @@ var (
 )
 
 // DeleteCourt method
-func DeleteCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func DeleteCourt(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionDeleteCourt
 	DebugVerbose(f, requestID, "")
 
 	userID, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	id, err := GetIntegerFromRequest(f, requestID, "id", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -40,7 +38,7 @@ func DeleteCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clie
 	if err != nil {
 		message := fmt.Sprintf("Could not load person [%d]", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 		return
 	}
 
@@ -48,23 +46,23 @@ func DeleteCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clie
 	if err != nil {
 		message := fmt.Sprintf("Person [%d] is not allowed to edit court", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyForbidden(requestID, responder, message)
 		return
 	}
 
 	c := model.Court{ID: id}
 	err = c.DeleteCourtTx(db)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		f.DebugVerbose(err.Error())
 		f.DumpError(err, "Could not update publications")
 		return
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -17,19 +15,19 @@ var (
 )
 
 // DeletePerson method
-func DeletePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func DeletePerson(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionDeletePerson
 	DebugVerbose(f, requestID, "")
 
 	userID, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	personID, err := GetIntegerFromRequest(f, requestID, "id", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -40,7 +38,7 @@ func DeletePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	if err != nil {
 		message := fmt.Sprintf("Could not load person [%d]", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 		return
 	}
 
@@ -49,30 +47,30 @@ func DeletePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 		if err != nil {
 			message := "Not allowed to delete self"
 			DebugVerbose(f, requestID, message)
-			ReplyForbidden(requestID, client, replyTopic, message)
+			ReplyForbidden(requestID, responder, message)
 		}
 	} else {
 		err = user.CanEditOtherPeople()
 		if err != nil {
 			message := "Not allowed to delete other people"
 			DebugVerbose(f, requestID, message)
-			ReplyForbidden(requestID, client, replyTopic, message)
+			ReplyForbidden(requestID, responder, message)
 		}
 	}
 
 	p := model.FullPerson{ID: personID}
 	err = p.DeletePerson(db)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		f.DebugVerbose(err.Error())
 		f.DumpError(err, "Could not update publications")
 		return
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
@@ -0,0 +1,118 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionDispatch = debug.NewFunction(pkg, "Dispatch")
+)
+
+// Dispatcher runs the handler for each command on a pool of workers. Requests arrive over both mqtt
+// and http, and go through the same dispatcher so that they are ordered against each other
+type Dispatcher struct {
+	db          *sql.DB
+	cfg         *config.Config
+	handlers    map[string]Handler
+	orderings   map[string]KeyFunc
+	idempotent  map[string]ScopeFunc
+	pool        *Pool
+	idempotency *IdempotencyCache
+}
+
+// NewDispatcher function. The idempotent commands are those whose replies may be replayed for a
+// repeated idempotency key: commands which change something, each with the scope of its keys
+func NewDispatcher(db *sql.DB, cfg *config.Config, handlers map[string]Handler, orderings map[string]KeyFunc, idempotent map[string]ScopeFunc) *Dispatcher {
+	pool := NewPool(cfg.Requests.Workers, cfg.Requests.QueueSize)
+	idempotency := NewIdempotencyCache(cfg.IdempotencyWindow)
+	return &Dispatcher{db: db, cfg: cfg, handlers: handlers, orderings: orderings, idempotent: idempotent, pool: pool, idempotency: idempotency}
+}
+
+// Dispatch queues a request for the handler of the command. The returned channel is closed when the
+// handler has finished. If the command is not found, false is returned and nothing is queued.
+//
+// When the request carries an idempotency key which the same client already sent with the same command
+// within the window, the handler is not run again and the stored reply is given instead. Only the
+// idempotent commands are replayed, so a reply holding tokens or private data is never stored
+func (d *Dispatcher) Dispatch(requestID int, command string, idempotencyKey string, responder Responder, data *map[string]interface{}) (<-chan struct{}, bool) {
+	f := functionDispatch
+
+	handler, ok := d.handlers[command]
+	if !ok {
+		return nil, false
+	}
+
+	done := make(chan struct{})
+	start := time.Now()
+
+	// the metrics record the status of the reply and the time from arrival to reply, which includes
+	// any time spent waiting for a worker
+	metrics := &statusResponder{responder: responder}
+	responder = metrics
+
+	finish := func() {
+		status := metrics.status
+		if status == 0 {
+			status = StatusInternalServerError
+		}
+		model.MetricsData.RecordCommand(command, status, time.Since(start))
+		close(done)
+	}
+
+	run := func() {
+		handler(d.db, d.cfg, requestID, responder, data)
+	}
+
+	key := ""
+	scope, ok := d.idempotent[command]
+	if idempotencyKey != "" && ok {
+		client, ok := scope(requestID, data)
+		if ok {
+			key = fmt.Sprintf("%s/%s/%s", command, client, idempotencyKey)
+		}
+	}
+
+	if key != "" {
+		entry, first := d.idempotency.begin(key)
+		if !first {
+			DebugVerbose(f, requestID, "Repeated idempotency key: %s", key)
+			go func() {
+				defer finish()
+				<-entry.done
+				if entry.reply == nil {
+					ReplyInternalServerError(requestID, responder, "the original request did not reply")
+					return
+				}
+				responder.Respond(requestID, entry.reply)
+			}()
+			return done, true
+		}
+
+		recorder := &recordingResponder{responder: responder}
+		run = func() {
+			defer func() {
+				d.idempotency.end(key, entry, recorder.reply)
+			}()
+			handler(d.db, d.cfg, requestID, recorder, data)
+		}
+	}
+
+	keys := []string{}
+	ordering, ok := d.orderings[command]
+	if ok {
+		keys = ordering(data)
+	}
+
+	d.pool.Submit(keys, func() {
+		defer finish()
+		run()
+	})
+
+	return done, true
+}
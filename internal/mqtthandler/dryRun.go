@@ -0,0 +1,32 @@
+package mqtthandler
+
+import (
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+// getDryRun reads the optional 'dryRun' field, which asks for a preview of a change without making it
+func getDryRun(f *debug.Function, requestID int, data *map[string]interface{}) (bool, error) {
+	return GetOptionalBoolFromRequest(f, requestID, "dryRun", data, false)
+}
+
+// dryRunReply lists the positions of the court and the waiting list as the change would leave them,
+// and for a fill, who would be picked and why
+func dryRunReply(preview *model.Preview, selections []model.Selection) interface{} {
+
+	reply := struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		DryRun  bool   `json:"dryRun"`
+		*model.Preview
+		Selections []model.Selection `json:"selections,omitempty"`
+	}{
+		Status:     StatusOK,
+		Message:    "ok",
+		DryRun:     true,
+		Preview:    preview,
+		Selections: selections,
+	}
+
+	return reply
+}
@@ -0,0 +1,60 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionFillAllCourts = debug.NewFunction(pkg, "FillAllCourts")
+)
+
+// FillAllCourts method fills the empty positions of every court from the waiting list in one go
+func FillAllCourts(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionFillAllCourts
+	DebugVerbose(f, requestID, "")
+
+	_, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	strategy, err := getFillStrategy(f, requestID, cfg, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	courts, err := model.FillAllCourts(db, strategy)
+	if err != nil {
+		message := "problem filling the courts"
+		Dump(f, requestID, message)
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := err.Error()
+		DebugVerbose(f, requestID, message)
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int               `json:"status"`
+		Message string            `json:"message"`
+		Courts  []model.CourtFill `json:"courts"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Courts:  courts,
+	}
+
+	Reply(requestID, responder, reply)
+}
@@ -1,11 +1,10 @@
 package mqtthandler
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -16,50 +15,97 @@ var (
 	functionFillCourt = debug.NewFunction(pkg, "FillCourt")
 )
 
-// FillCourt method
-func FillCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+// FillCourt method. With 'dryRun' the court is not filled, and the reply shows who would be picked
+func FillCourt(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionFillCourt
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	courtID, err := GetIntegerFromRequest(f, requestID, "courtID", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	strategy, err := getFillStrategy(f, requestID, cfg, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	dryRun, err := getDryRun(f, requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
 	DebugVerbose(f, requestID, "courtID: %d", courtID)
 
-	positions, err := model.FillCourt(db, courtID)
+	if dryRun {
+		var selections []model.Selection
+		preview, err := model.DryRun(db, courtID, func(ctx context.Context, tx model.Querier) (err error) {
+			_, selections, err = model.FillCourtTx(ctx, tx, courtID, strategy)
+			return err
+		})
+		if err != nil {
+			DebugVerbose(f, requestID, err.Error())
+			ReplyError(requestID, responder, err)
+			return
+		}
+
+		Reply(requestID, responder, dryRunReply(preview, selections))
+		return
+	}
+
+	positions, selections, err := model.FillCourt(db, courtID, strategy)
 	if err != nil {
 		message := "problem filling court"
 		d := Dump(f, requestID, message)
 		d.AddString("courtID", fmt.Sprintf("%d", courtID))
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyError(requestID, responder, err)
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		message := err.Error()
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
+	Reply(requestID, responder, fillCourtReply(positions, selections))
+}
+
+// getFillStrategy returns the strategy named by the optional 'strategy' field, or else by the configuration
+func getFillStrategy(f *debug.Function, requestID int, cfg *config.Config, data *map[string]interface{}) (model.FillStrategy, error) {
+
+	name, err := GetOptionalStringFromRequest(f, requestID, "strategy", data, cfg.Filling.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.LookupFillStrategy(name)
+}
+
+// fillCourtReply lists the positions of the court, and who was picked for the empty positions and why
+func fillCourtReply(positions []model.Position, selections []model.Selection) interface{} {
+
 	reply := struct {
-		Status    int              `json:"status"`
-		Message   string           `json:"message"`
-		Positions []model.Position `json:"positions"`
+		Status     int               `json:"status"`
+		Message    string            `json:"message"`
+		Positions  []model.Position  `json:"positions"`
+		Selections []model.Selection `json:"selections"`
 	}{
-		Status:    StatusOK,
-		Message:   "ok",
-		Positions: positions,
+		Status:     StatusOK,
+		Message:    "ok",
+		Positions:  positions,
+		Selections: selections,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	return reply
 }
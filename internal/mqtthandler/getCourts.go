@@ -3,8 +3,6 @@ package mqtthandler
 import (
 	"database/sql"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -14,19 +12,19 @@ var (
 	functionGetCourts = debug.NewFunction(pkg, "GetCourts")
 )
 
-func GetCourts(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func GetCourts(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionGetCourts
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	listOfCourts, err := model.ListCourts(db)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -40,5 +38,5 @@ func GetCourts(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client
 		ListOfCourts: listOfCourts,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
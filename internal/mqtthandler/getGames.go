@@ -0,0 +1,141 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionGetGames        = debug.NewFunction(pkg, "GetGames")
+	functionParseGameFilter = debug.NewFunction(pkg, "parseGameFilter")
+)
+
+const (
+	defaultGamesPageSize = 20
+	maxGamesPageSize     = 100
+)
+
+// GetGames method
+func GetGames(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionGetGames
+	DebugVerbose(f, requestID, "")
+
+	_, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	filter, err := parseGameFilter(requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	games, err := model.ListGames(db, *filter)
+	if err != nil {
+		message := "problem listing games"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status   int          `json:"status"`
+		Message  string       `json:"message"`
+		Page     int          `json:"page"`
+		PageSize int          `json:"pageSize"`
+		Games    []model.Game `json:"games"`
+	}{
+		Status:   StatusOK,
+		Message:  "ok",
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		Games:    games,
+	}
+
+	Reply(requestID, responder, reply)
+}
+
+// parseGameFilter reads the optional 'court', 'person', 'session', 'from', 'to', 'page' and 'pageSize' fields.
+// The dates are either 'yyyy-mm-dd' or RFC 3339 timestamps
+func parseGameFilter(requestID int, data *map[string]interface{}) (*model.GameFilter, error) {
+	f := functionParseGameFilter
+
+	filter := new(model.GameFilter)
+	var err error
+
+	filter.Court, err = GetOptionalIntegerFromRequest(f, requestID, "court", data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Person, err = GetOptionalIntegerFromRequest(f, requestID, "person", data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Session, err = GetOptionalIntegerFromRequest(f, requestID, "session", data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Page, err = GetOptionalIntegerFromRequest(f, requestID, "page", data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Page < 0 {
+		return nil, fmt.Errorf("unexpected page: %d", filter.Page)
+	}
+
+	filter.PageSize, err = GetOptionalIntegerFromRequest(f, requestID, "pageSize", data, defaultGamesPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if filter.PageSize <= 0 || filter.PageSize > maxGamesPageSize {
+		return nil, fmt.Errorf("unexpected pageSize: %d, expected 1 to %d", filter.PageSize, maxGamesPageSize)
+	}
+
+	filter.From, _, err = getOptionalDate(f, requestID, "from", data)
+	if err != nil {
+		return nil, err
+	}
+
+	// a 'to' date includes the whole of that day
+	to, dateOnly, err := getOptionalDate(f, requestID, "to", data)
+	if err != nil {
+		return nil, err
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	filter.To = to
+
+	return filter, nil
+}
+
+// getOptionalDate returns the time, and whether it was given as a date without a time of day
+func getOptionalDate(f *debug.Function, requestID int, key string, data *map[string]interface{}) (time.Time, bool, error) {
+
+	value, err := GetOptionalStringFromRequest(f, requestID, key, data, "")
+	if err != nil || value == "" {
+		return time.Time{}, false, err
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err == nil {
+		return date, true, nil
+	}
+
+	date, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("unexpected date for the key [%s]: %s", key, value)
+	}
+
+	return date, false, nil
+}
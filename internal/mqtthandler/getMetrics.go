@@ -0,0 +1,55 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionGetMetrics = debug.NewFunction(pkg, "GetMetrics")
+)
+
+// GetMetrics method
+func GetMetrics(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionGetMetrics
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanGetMetrics()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to get the metrics", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int            `json:"status"`
+		Message string         `json:"message"`
+		Metrics *model.Metrics `json:"metrics"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Metrics: model.MetricsData.Snapshot(),
+	}
+
+	Reply(requestID, responder, reply)
+}
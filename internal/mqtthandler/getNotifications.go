@@ -0,0 +1,52 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionGetNotifications = debug.NewFunction(pkg, "GetNotifications")
+)
+
+// GetNotifications method returns the user's notifications after 'after', the id of the last one they
+// received. A client asks for them when it connects, to catch up on any it missed. The notifications up
+// to 'after' are taken as received, and are deleted
+func GetNotifications(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionGetNotifications
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	after, err := GetOptionalIntegerFromRequest(f, requestID, "after", data, 0)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	list, err := model.ListNotifications(db, userID, after)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	reply := struct {
+		Status        int                  `json:"status"`
+		Message       string               `json:"message"`
+		Notifications []model.Notification `json:"notifications"`
+	}{
+		Status:        StatusOK,
+		Message:       "ok",
+		Notifications: list,
+	}
+
+	Reply(requestID, responder, reply)
+}
@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -25,32 +23,32 @@ func init() {
 	filters["suspended"] = `WHERE status = 'suspended'`
 }
 
-func GetPeople(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func GetPeople(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionGetPeople
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	filter, err := GetStringFromRequest(f, requestID, "filter", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
 	var whereClause string
 	var ok bool
 	if whereClause, ok = filters[filter]; !ok {
-		ReplyBadRequest(requestID, client, replyTopic, fmt.Sprintf("unexpected filter name: '%s'", filter))
+		ReplyBadRequest(requestID, responder, fmt.Sprintf("unexpected filter name: '%s'", filter))
 		return
 	}
 
 	listOfFullPeople, err := model.ListPeople(db, whereClause)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -69,5 +67,5 @@ func GetPeople(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client
 		ListOfPeople: listOfPeople,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
@@ -3,8 +3,6 @@ package mqtthandler
 import (
 	"database/sql"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -15,19 +13,19 @@ var (
 )
 
 // GetPerson method
-func GetPerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func GetPerson(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionGetPerson
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	id, err := GetIntegerFromRequest(f, requestID, "id", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -36,7 +34,7 @@ func GetPerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client
 	p := model.FullPerson{ID: id}
 	err = p.LoadPerson(db)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -50,5 +48,5 @@ func GetPerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client
 		Person:  *p.ToLimited(),
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -16,19 +14,19 @@ var (
 )
 
 // GetWaiters method
-func GetWaiters(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func GetWaiters(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionGetWaiters
 	DebugVerbose(f, requestID, "")
 
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	waiters, err := model.ListWaitersTx(context.Background(), db)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -38,7 +36,7 @@ func GetWaiters(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 		p := model.FullPerson{ID: waiter.Person}
 		err := p.LoadPersonTx(context.Background(), db)
 		if err != nil {
-			ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+			ReplyInternalServerError(requestID, responder, err.Error())
 			return
 		}
 
@@ -46,6 +44,8 @@ func GetWaiters(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 		w.PersonId.ID = waiter.Person
 		w.PersonId.Knownas = p.Knownas
 		w.Start = waiter.Start.Unix()
+		w.Paused = waiter.Paused
+		w.Priority = waiter.Priority
 
 		list = append(list, w)
 	}
@@ -60,5 +60,5 @@ func GetWaiters(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 		ListOfWaiters: list,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
@@ -0,0 +1,91 @@
+package mqtthandler
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyCache remembers the reply to each request which carried an idempotency key, so that a
+// client retrying the same request within the window gets the original reply instead of running the
+// command a second time
+type IdempotencyCache struct {
+	mutex   sync.Mutex
+	window  time.Duration
+	entries map[string]*idempotentEntry
+}
+
+type idempotentEntry struct {
+	expires time.Time
+	done    chan struct{}
+	reply   interface{}
+}
+
+// NewIdempotencyCache function
+func NewIdempotencyCache(window time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{window: window, entries: map[string]*idempotentEntry{}}
+}
+
+// begin returns the entry for the key, and whether the entry is new. A new entry must be completed
+// by calling 'end' once the handler has run
+func (cache *IdempotencyCache) begin(key string) (*idempotentEntry, bool) {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := time.Now()
+	for k, entry := range cache.entries {
+		if now.After(entry.expires) {
+			delete(cache.entries, k)
+		}
+	}
+
+	entry, ok := cache.entries[key]
+	if ok {
+		return entry, false
+	}
+
+	entry = &idempotentEntry{expires: now.Add(cache.window), done: make(chan struct{})}
+	cache.entries[key] = entry
+	return entry, true
+}
+
+// end stores the reply. If the handler did not reply, the entry is dropped so a retry runs again
+func (cache *IdempotencyCache) end(key string, entry *idempotentEntry, reply interface{}) {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry.reply = reply
+	if reply == nil {
+		delete(cache.entries, key)
+	}
+	close(entry.done)
+}
+
+// ScopeFunc returns who a request is from, so that an idempotency key is only matched against the
+// requests of the same client. It returns false when the request has no scope, and is not replayed
+type ScopeFunc func(requestID int, data *map[string]interface{}) (string, bool)
+
+// UserScope scopes a request by the signed in user
+func UserScope() ScopeFunc {
+	return func(requestID int, data *map[string]interface{}) (string, bool) {
+		userID, err := checkAuthenticated(requestID, data)
+		if err != nil {
+			return "", false
+		}
+		return strconv.Itoa(userID), true
+	}
+}
+
+// FieldScope scopes a request by the value of a field, for a command which is sent before signing in
+func FieldScope(field string) ScopeFunc {
+	return func(requestID int, data *map[string]interface{}) (string, bool) {
+		value, ok := (*data)[field]
+		if !ok || value == "" {
+			return "", false
+		}
+		return fmt.Sprintf("%v", value), true
+	}
+}
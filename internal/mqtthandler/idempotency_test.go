@@ -0,0 +1,30 @@
+package mqtthandler
+
+import (
+	"testing"
+)
+
+func TestFieldScope(t *testing.T) {
+
+	scope := FieldScope("email")
+
+	client, ok := scope(0, &map[string]interface{}{"email": "bob@ntl.co.uk"})
+	if !ok || client != "bob@ntl.co.uk" {
+		t.Logf("Unexpected scope: '%s', %t", client, ok)
+		t.FailNow()
+	}
+
+	for _, data := range []map[string]interface{}{{}, {"email": ""}} {
+		_, ok = scope(0, &data)
+		if ok {
+			t.Logf("Unexpected scope for %v", data)
+			t.FailNow()
+		}
+	}
+
+	_, ok = UserScope()(0, &map[string]interface{}{})
+	if ok {
+		t.Log("Unexpected scope for a request which is not signed in")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,110 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionMovePlayer      = debug.NewFunction(pkg, "MovePlayer")
+	functionParseCourtPlace = debug.NewFunction(pkg, "parseCourtPlace")
+)
+
+// MovePlayer method moves a player from one position to an empty one, on the same court or
+// another, without sending them back to the waiting list. Each of 'from' and 'to' holds a 'court', an
+// 'index' and the 'original' person the client expects there, which is left out for an empty position
+func MovePlayer(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionMovePlayer
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanEditGame()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to move players", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	from, err := parseCourtPlace(requestID, data, "from")
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	to, err := parseCourtPlace(requestID, data, "to")
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	err = model.MovePlayer(db, *from, *to)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	ReplyOK(requestID, responder)
+}
+
+// parseCourtPlace reads a position on a court from the named field of the request data
+func parseCourtPlace(requestID int, data *map[string]interface{}, fieldName string) (*model.CourtPlace, error) {
+	f := functionParseCourtPlace
+	DebugVerbose(f, requestID, "")
+
+	x, found := (*data)[fieldName]
+	if !found {
+		return nil, fmt.Errorf("missing field: '%s'", fieldName)
+	}
+
+	placeMap, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: '%s': %T", fieldName, x)
+	}
+
+	court, err := GetIntegerFromRequest(f, requestID, "court", &placeMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// the capacity of the court is checked against the index when the player is moved
+	index, err := parseGamePositionIndex(requestID, &placeMap, 0, model.DoublesCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := parseGamePosition(requestID, &placeMap, "original")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CourtPlace{Court: court, Index: index, Original: original}, nil
+}
@@ -0,0 +1,90 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionMoveWaiter = debug.NewFunction(pkg, "MoveWaiter")
+)
+
+// MoveWaiter method moves the waiter 'id' to the 'index' in the queue, counting from the front, or by
+// 'offset' places, where a negative offset moves the waiter forward
+func MoveWaiter(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionMoveWaiter
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	personID, err := GetIntegerFromRequest(f, requestID, "id", data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	_, relative := (*data)["offset"]
+	var index int
+	if relative {
+		index, err = GetIntegerFromRequest(f, requestID, "offset", data)
+	} else {
+		index, err = GetIntegerFromRequest(f, requestID, "index", data)
+	}
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanReorderWaiters()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to reorder the waiters", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	index, err = model.MoveWaiter(db, personID, index, relative)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		Index   int    `json:"index"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Index:   index,
+	}
+
+	Reply(requestID, responder, reply)
+}
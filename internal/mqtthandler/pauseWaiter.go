@@ -0,0 +1,20 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionPauseWaiter = debug.NewFunction(pkg, "PauseWaiter")
+)
+
+// PauseWaiter method lets a waiter sit out without losing their place in the queue. The person is the user, unless an admin names someone else with 'id'
+func PauseWaiter(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	selfOrAdmin(functionPauseWaiter, db, cfg, requestID, responder, data, "pause", func(db *sql.DB, personID int) error {
+		return model.SetWaiterPaused(db, personID, true)
+	})
+}
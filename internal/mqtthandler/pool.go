@@ -0,0 +1,207 @@
+package mqtthandler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+var (
+	functionRunJob = debug.NewFunction(pkg, "runJob")
+)
+
+// Pool runs requests on a fixed number of workers. Each request names the keys it touches (for
+// example a court, a person or the waiting list), and requests which share a key run one at a time
+// in the order they were submitted. Requests with no keys in common run in parallel
+type Pool struct {
+	mutex    sync.Mutex
+	ready    *sync.Cond
+	space    *sync.Cond
+	queues   map[string][]*job
+	runnable []*job
+	pending  int
+	capacity int
+}
+
+type job struct {
+	keys    []string
+	blocked int
+	run     func()
+}
+
+// NewPool starts a pool of workers, which holds at most 'capacity' requests before Submit blocks
+func NewPool(workers int, capacity int) *Pool {
+
+	pool := &Pool{queues: map[string][]*job{}, capacity: capacity}
+	pool.ready = sync.NewCond(&pool.mutex)
+	pool.space = sync.NewCond(&pool.mutex)
+
+	for i := 0; i < workers; i++ {
+		go pool.worker()
+	}
+
+	return pool
+}
+
+// Submit queues a request behind the earlier requests which share any of its keys
+func (pool *Pool) Submit(keys []string, run func()) {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for pool.pending >= pool.capacity {
+		pool.space.Wait()
+	}
+	pool.pending++
+
+	j := &job{keys: uniqueKeys(keys), run: run}
+
+	for _, key := range j.keys {
+		queue := pool.queues[key]
+		if len(queue) > 0 {
+			j.blocked++
+		}
+		pool.queues[key] = append(queue, j)
+	}
+
+	if j.blocked == 0 {
+		pool.makeRunnable(j)
+	}
+}
+
+func (pool *Pool) worker() {
+	for {
+		pool.mutex.Lock()
+		for len(pool.runnable) == 0 {
+			pool.ready.Wait()
+		}
+		j := pool.runnable[0]
+		pool.runnable = pool.runnable[1:]
+		pool.mutex.Unlock()
+
+		runJob(j)
+		pool.finish(j)
+	}
+}
+
+// runJob runs a request, so that a panic in a handler does not stop the worker
+func runJob(j *job) {
+	f := functionRunJob
+
+	defer func() {
+		if r := recover(); r != nil {
+			f.Errorf("Request with keys %v failed: %v", j.keys, r)
+		}
+	}()
+
+	j.run()
+}
+
+// finish releases the keys of a completed request, and lets the next request on each key run
+func (pool *Pool) finish(j *job) {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for _, key := range j.keys {
+		queue := pool.queues[key][1:]
+		if len(queue) == 0 {
+			delete(pool.queues, key)
+			continue
+		}
+		pool.queues[key] = queue
+
+		next := queue[0]
+		next.blocked--
+		if next.blocked == 0 {
+			pool.makeRunnable(next)
+		}
+	}
+
+	pool.pending--
+	pool.space.Signal()
+}
+
+func (pool *Pool) makeRunnable(j *job) {
+	pool.runnable = append(pool.runnable, j)
+	pool.ready.Signal()
+}
+
+func uniqueKeys(keys []string) []string {
+
+	seen := map[string]bool{}
+	list := []string{}
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			list = append(list, key)
+		}
+	}
+	return list
+}
+
+// KeyFunc returns the keys a request touches, from the request data
+type KeyFunc func(data *map[string]interface{}) []string
+
+const (
+	// WaitingKey orders the requests which take people from, or return people to, the waiting list
+	WaitingKey = "waiting"
+)
+
+// CourtKey orders the requests on the court whose id is in the given field
+func CourtKey(field string) KeyFunc {
+	return fieldKey("court", field)
+}
+
+// courtKey returns the key of a court, as CourtKey makes it from a request
+func courtKey(courtID int) string {
+	return fmt.Sprintf("court:%d", courtID)
+}
+
+// PersonKey orders the requests on the person whose id is in the given field
+func PersonKey(field string) KeyFunc {
+	return fieldKey("person", field)
+}
+
+// PlaceCourtKey orders the requests on the court of the position held in the given field, which is
+// an object with a 'court'
+func PlaceCourtKey(field string) KeyFunc {
+	return func(data *map[string]interface{}) []string {
+		place, ok := (*data)[field].(map[string]interface{})
+		if !ok {
+			return []string{}
+		}
+		return fieldKey("court", "court")(&place)
+	}
+}
+
+// Waiting orders the requests on the waiting list
+func Waiting() KeyFunc {
+	return func(data *map[string]interface{}) []string {
+		return []string{WaitingKey}
+	}
+}
+
+// Keys combines the keys of several functions
+func Keys(funcs ...KeyFunc) KeyFunc {
+	return func(data *map[string]interface{}) []string {
+		keys := []string{}
+		for _, fn := range funcs {
+			keys = append(keys, fn(data)...)
+		}
+		return keys
+	}
+}
+
+// fieldKey returns a key made from the value of a field, or no key if the field is missing, in
+// which case the handler rejects the request anyway
+func fieldKey(kind string, field string) KeyFunc {
+	return func(data *map[string]interface{}) []string {
+		value, ok := (*data)[field]
+		if !ok {
+			return []string{}
+		}
+		return []string{fmt.Sprintf("%s:%v", kind, value)}
+	}
+}
@@ -0,0 +1,72 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionRecalculateRatings = debug.NewFunction(pkg, "RecalculateRatings")
+)
+
+// RecalculateRatings method replays the whole history of scored games to rebuild everyone's rating
+func RecalculateRatings(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionRecalculateRatings
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanRecalculateRatings()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to recalculate the ratings", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	count, err := model.RecalculateRatings(db)
+	if err != nil {
+		message := "problem recalculating the ratings"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		Games   int    `json:"games"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Games:   count,
+	}
+
+	Reply(requestID, responder, reply)
+}
@@ -0,0 +1,150 @@
+package mqtthandler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionRecordScore = debug.NewFunction(pkg, "RecordScore")
+	functionParseSets   = debug.NewFunction(pkg, "parseSets")
+)
+
+// RecordScore method attaches the set scores to a game, given by its 'game' id, or to the latest game
+// on a 'court'
+func RecordScore(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	f := functionRecordScore
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	err = user.CanEditGame()
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to record a score", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	gameID, err := GetOptionalIntegerFromRequest(f, requestID, "game", data, 0)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	courtID, err := GetOptionalIntegerFromRequest(f, requestID, "court", data, 0)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	if gameID <= 0 && courtID <= 0 {
+		ReplyBadRequest(requestID, responder, "either the 'game' or the 'court' is needed")
+		return
+	}
+
+	bestOf, err := GetOptionalIntegerFromRequest(f, requestID, "bestOf", data, cfg.Scoring.BestOf)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	sets, err := parseSets(requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	err = model.RunInTransaction(db, func(ctx context.Context, tx model.Querier) error {
+		if gameID <= 0 {
+			gameID, err = model.LatestGameOnCourtTx(ctx, tx, courtID)
+			if err != nil {
+				return err
+			}
+		}
+		return model.RecordScoreTx(ctx, tx, gameID, sets, bestOf)
+	})
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	reply := struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		Game    int    `json:"game"`
+	}{
+		Status:  StatusOK,
+		Message: "ok",
+		Game:    gameID,
+	}
+
+	Reply(requestID, responder, reply)
+}
+
+// parseSets reads the 'sets' list, where each set is an object holding the points of 'team1' and 'team2'
+func parseSets(requestID int, data *map[string]interface{}) ([]model.SetScore, error) {
+	f := functionParseSets
+	DebugVerbose(f, requestID, "")
+
+	x, ok := (*data)["sets"]
+	if !ok {
+		return nil, codeerror.NewBadRequest("missing field: 'sets'")
+	}
+
+	list, ok := x.([]interface{})
+	if !ok {
+		return nil, codeerror.NewBadRequest(fmt.Sprintf("unexpected type for 'sets': %#v", x))
+	}
+
+	sets := []model.SetScore{}
+	for i, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, codeerror.NewBadRequest(fmt.Sprintf("unexpected type for sets[%d]: %#v", i, item))
+		}
+
+		team1, err := GetIntegerFromRequest(f, requestID, "team1", &object)
+		if err != nil {
+			return nil, codeerror.NewBadRequest(fmt.Sprintf("sets[%d]: %s", i, err.Error()))
+		}
+
+		team2, err := GetIntegerFromRequest(f, requestID, "team2", &object)
+		if err != nil {
+			return nil, codeerror.NewBadRequest(fmt.Sprintf("sets[%d]: %s", i, err.Error()))
+		}
+
+		sets = append(sets, model.SetScore{Team1: team1, Team2: team2})
+	}
+
+	return sets, nil
+}
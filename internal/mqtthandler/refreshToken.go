@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/basic"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
@@ -17,7 +15,7 @@ var (
 )
 
 // RefreshToken method
-func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionRefreshToken
 	DebugVerbose(f, requestID, "")
 
@@ -26,7 +24,7 @@ func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	// *********************************************************************
 	_, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
@@ -35,7 +33,7 @@ func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	// *********************************************************************
 	refreshToken, err := GetStringFromRequest(f, requestID, "refreshToken", data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
@@ -43,7 +41,7 @@ func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	if err != nil {
 		message := fmt.Sprintf("refreshToken not valid: %s", err.Error())
 		DebugVerbose(f, requestID, message)
-		ReplyUnAuthorised(requestID, client, replyTopic, message)
+		ReplyUnAuthorised(requestID, responder, message)
 		return
 	}
 
@@ -53,7 +51,7 @@ func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	DebugVerbose(f, requestID, "accessTokenExpiry:  %10s     expires at: %s", cfg.AccessTokenExpiry, time.Now().Add(cfg.AccessTokenExpiry))
 	newAccessToken, err := basic.GenerateToken(claims.ID, claims.Request, cfg.AccessTokenExpiry)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -67,5 +65,5 @@ func RefreshToken(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 		AccessToken: newAccessToken,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
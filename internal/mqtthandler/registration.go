@@ -3,7 +3,6 @@ package mqtthandler
 import (
 	"database/sql"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/jackc/pgx"
 
 	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
@@ -17,19 +16,19 @@ var (
 	functionRegister = debug.NewFunction(pkg, "Register")
 )
 
-func Register(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func Register(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionRegister
 	DebugVerbose(f, requestID, "")
 
 	registration, err := model.NewRegistrationFromMap(data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
 	p, err := registration.ToPerson()
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -44,16 +43,16 @@ func Register(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client,
 			}
 		}
 
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		f.DebugVerbose(err.Error())
 		f.DumpError(err, "Could not update publications")
 		return
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
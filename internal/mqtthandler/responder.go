@@ -0,0 +1,118 @@
+package mqtthandler
+
+import (
+	"encoding/json"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+var (
+	functionCorrelatedRespond = debug.NewFunction(pkg, "CorrelatedRespond")
+)
+
+// Responder sends the reply to a request back to whoever made it
+type Responder interface {
+	Respond(requestID int, object interface{})
+}
+
+// MqttResponder replies by publishing to the reply topic given in the request
+type MqttResponder struct {
+	Client mqtt.Client
+	Topic  string
+}
+
+// NewMqttResponder function
+func NewMqttResponder(client mqtt.Client, topic string) *MqttResponder {
+	return &MqttResponder{Client: client, Topic: topic}
+}
+
+// Respond method
+func (r *MqttResponder) Respond(requestID int, object interface{}) {
+	Publish(requestID, r.Client, r.Topic, object)
+}
+
+// CorrelatedResponder adds the client's correlation ID to every reply
+type CorrelatedResponder struct {
+	Responder     Responder
+	CorrelationID string
+}
+
+// NewCorrelatedResponder returns a responder which echoes the correlation ID, or the responder itself
+// when the client did not supply one
+func NewCorrelatedResponder(responder Responder, correlationID string) Responder {
+	if correlationID == "" {
+		return responder
+	}
+	return &CorrelatedResponder{Responder: responder, CorrelationID: correlationID}
+}
+
+// Respond method
+func (r *CorrelatedResponder) Respond(requestID int, object interface{}) {
+	f := functionCorrelatedRespond
+
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		DumpError(f, err, requestID, "Could not marshal the reply")
+		r.Responder.Respond(requestID, object)
+		return
+	}
+
+	reply := map[string]interface{}{}
+	err = json.Unmarshal(bytes, &reply)
+	if err != nil {
+		DumpError(f, err, requestID, "The reply is not an object")
+		r.Responder.Respond(requestID, object)
+		return
+	}
+
+	reply["correlationId"] = r.CorrelationID
+	r.Responder.Respond(requestID, reply)
+}
+
+// recordingResponder keeps a copy of the reply, so it can be given again to a repeated request
+type recordingResponder struct {
+	responder Responder
+	reply     interface{}
+}
+
+// Respond method
+func (r *recordingResponder) Respond(requestID int, object interface{}) {
+	if r.reply == nil {
+		r.reply = object
+	}
+	r.responder.Respond(requestID, object)
+}
+
+// statusResponder keeps the status of the reply, for the metrics
+type statusResponder struct {
+	responder Responder
+	status    int
+}
+
+// Respond method
+func (r *statusResponder) Respond(requestID int, object interface{}) {
+	if r.status == 0 {
+		r.status = replyStatusOf(object)
+	}
+	r.responder.Respond(requestID, object)
+}
+
+// replyStatusOf returns the 'status' field of a reply
+func replyStatusOf(object interface{}) int {
+
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return StatusInternalServerError
+	}
+
+	reply := struct {
+		Status int `json:"status"`
+	}{}
+	json.Unmarshal(bytes, &reply)
+
+	if reply.Status == 0 {
+		return StatusOK
+	}
+	return reply.Status
+}
@@ -0,0 +1,20 @@
+package mqtthandler
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionResumeWaiter = debug.NewFunction(pkg, "ResumeWaiter")
+)
+
+// ResumeWaiter method brings a paused waiter back to their original place in the queue. The person is the user, unless an admin names someone else with 'id'
+func ResumeWaiter(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
+	selfOrAdmin(functionResumeWaiter, db, cfg, requestID, responder, data, "resume", func(db *sql.DB, personID int) error {
+		return model.SetWaiterPaused(db, personID, false)
+	})
+}
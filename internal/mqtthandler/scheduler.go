@@ -0,0 +1,156 @@
+package mqtthandler
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionStartScheduler = debug.NewFunction(pkg, "StartScheduler")
+	functionTick           = debug.NewFunction(pkg, "tick")
+	functionRotateCourts   = debug.NewFunction(pkg, "rotateCourts")
+	functionExpireCalls    = debug.NewFunction(pkg, "expireCalls")
+)
+
+// StartScheduler runs the timed work of the server every interval. The work goes through the pool
+// like a request, with the waiting key and the key of every court, so it is ordered against the
+// requests which change the waiting list or any of the courts
+func (d *Dispatcher) StartScheduler(interval time.Duration) {
+	f := functionStartScheduler
+
+	// a tick is skipped while the previous one is still queued or running
+	var busy int32
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if !atomic.CompareAndSwapInt32(&busy, 0, 1) {
+				continue
+			}
+
+			courts, err := model.ListCourts(d.db)
+			if err != nil {
+				f.DebugVerbose(err.Error())
+				atomic.StoreInt32(&busy, 0)
+				continue
+			}
+
+			keys := []string{WaitingKey}
+			held := map[int]bool{}
+			for _, court := range courts {
+				keys = append(keys, courtKey(court.ID))
+				held[court.ID] = true
+			}
+
+			d.pool.Submit(keys, func() {
+				defer atomic.StoreInt32(&busy, 0)
+				d.tick(held)
+			})
+		}
+	}()
+}
+
+// tick does the timed work once. While any game has a time limit, or anything changed, the publications
+// are updated, which keeps the countdown on 'getCourt/{id}' current. Only the courts whose keys are
+// held are rotated
+func (d *Dispatcher) tick(held map[int]bool) {
+	f := functionTick
+
+	limited := d.rotateCourts(held)
+	expired := d.expireCalls()
+
+	if !limited && !expired {
+		return
+	}
+
+	err := publisher.UpdatePublications(d.db, d.cfg)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		f.DumpError(err, "Could not update publications")
+	}
+}
+
+// rotateCourts clears each court whose game has run out of time, and fills it again when the
+// configuration asks for it. A court created since the keys were taken is left to the next tick. It
+// returns whether any game has a time limit
+func (d *Dispatcher) rotateCourts(held map[int]bool) bool {
+	f := functionRotateCourts
+
+	expired, limited, err := model.ListExpiredCourts(d.db)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return false
+	}
+
+	for _, courtID := range expired {
+		if !held[courtID] {
+			continue
+		}
+
+		f.DebugVerbose("the game on court [%d] has run out of time", courtID)
+
+		err = model.ClearCourt(d.db, courtID)
+		if err != nil {
+			f.DebugVerbose(err.Error())
+			f.DumpError(err, fmt.Sprintf("Could not clear court [%d]", courtID))
+			continue
+		}
+
+		if d.cfg.Rotation.Action != config.RotationRefill {
+			continue
+		}
+
+		strategy, err := model.LookupFillStrategy(d.cfg.Filling.Strategy)
+		if err != nil {
+			f.DebugVerbose(err.Error())
+			continue
+		}
+
+		_, _, err = model.FillCourt(d.db, courtID, strategy)
+		if err != nil {
+			f.DebugVerbose(err.Error())
+			f.DumpError(err, fmt.Sprintf("Could not fill court [%d]", courtID))
+		}
+	}
+
+	return limited
+}
+
+// expireCalls sends the people who did not accept their call in time back to the queue, as the
+// configuration says, and calls the next waiters. It returns whether any call ran out
+func (d *Dispatcher) expireCalls() bool {
+	f := functionExpireCalls
+
+	if d.cfg.CallTimeout <= 0 {
+		return false
+	}
+
+	strategy, err := model.LookupFillStrategy(d.cfg.Filling.Strategy)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return false
+	}
+
+	keepPlace := d.cfg.Calls.NoShow == config.NoShowKeepPlace
+
+	expired, err := model.ExpireCalls(d.db, d.cfg.CallTimeout, keepPlace, strategy)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		f.DumpError(err, "Could not expire the calls")
+		return false
+	}
+
+	for _, call := range expired {
+		f.DebugVerbose("person [%d] did not accept the call to court [%d]", call.PersonId.ID, call.Court)
+	}
+
+	return len(expired) > 0
+}
@@ -0,0 +1,70 @@
+package mqtthandler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/internal/publisher"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+// selfOrAdmin runs a command on one person, who is the user unless an admin names someone else with
+// 'id'. A player or inactive person may run it on themselves. The action names the command in the
+// reply when the user is not allowed
+func selfOrAdmin(f *debug.Function, db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}, action string, fn func(db *sql.DB, personID int) error) {
+	DebugVerbose(f, requestID, "")
+
+	userID, err := checkAuthenticated(requestID, data)
+	if err != nil {
+		ReplyUnAuthorised(requestID, responder, err.Error())
+		return
+	}
+
+	personID, err := GetOptionalIntegerFromRequest(f, requestID, "id", data, userID)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	DebugVerbose(f, requestID, "personID: %d", personID)
+
+	user := model.FullPerson{ID: userID}
+	err = user.LoadPerson(db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load person [%d]", userID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	if userID == personID {
+		err = user.CanCheckIn()
+	} else {
+		err = user.CanCheckInOtherPeople()
+	}
+	if err != nil {
+		message := fmt.Sprintf("Person [%d] is not allowed to %s person [%d]", userID, action, personID)
+		DebugVerbose(f, requestID, err.Error())
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	err = fn(db, personID)
+	if err != nil {
+		DebugVerbose(f, requestID, err.Error())
+		ReplyError(requestID, responder, err)
+		return
+	}
+
+	err = publisher.UpdatePublications(db, cfg)
+	if err != nil {
+		message := "problem updating publications"
+		DebugVerbose(f, requestID, err.Error())
+		ReplyInternalServerError(requestID, responder, message)
+		return
+	}
+
+	ReplyOK(requestID, responder)
+}
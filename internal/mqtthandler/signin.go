@@ -5,8 +5,6 @@ import (
 	"database/sql"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/basic"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
@@ -17,13 +15,13 @@ var (
 	functionSignin = debug.NewFunction(pkg, "Signin")
 )
 
-func Signin(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func Signin(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionSignin
 	DebugVerbose(f, requestID, "")
 
 	signin, err := model.NewSigninFromMap(data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -32,27 +30,27 @@ func Signin(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, r
 	p, err := model.FindPersonByEmail(context.Background(), db, email)
 	if err != nil {
 		f.DebugVerbose("FindPersonByEmail returned err: %s", err.Error())
-		ReplyBadRequest(requestID, client, replyTopic, "Not Authenticated")
+		ReplyBadRequest(requestID, responder, "Not Authenticated")
 		return
 	}
 
 	err = p.Authenticate(db, signin.Password)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
 	DebugVerbose(f, requestID, "accessTokenExpiry:  %10s     expires at: %s", cfg.AccessTokenExpiry, time.Now().Add(cfg.AccessTokenExpiry).Round(time.Second))
 	accessToken, err := basic.GenerateToken(p.ID, requestID, cfg.AccessTokenExpiry)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
 	DebugVerbose(f, requestID, "refreshTokenExpiry: %10s     expires at: %s", cfg.RefreshTokenExpiry, time.Now().Add(cfg.RefreshTokenExpiry).Round(time.Second))
 	refreshToken, err := basic.GenerateToken(p.ID, requestID, cfg.RefreshTokenExpiry)
 	if err != nil {
-		ReplyInternalServerError(requestID, client, replyTopic, err.Error())
+		ReplyInternalServerError(requestID, responder, err.Error())
 		return
 	}
 
@@ -75,5 +73,5 @@ func Signin(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, r
 		RefreshDelta: refreshDelta,
 	}
 
-	Reply(requestID, client, replyTopic, reply)
+	Reply(requestID, responder, reply)
 }
@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -17,19 +15,19 @@ var (
 )
 
 // UpdateCourt method
-func UpdateCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func UpdateCourt(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionUpdateCourt
 	DebugVerbose(f, requestID, "")
 
 	userID, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	courtID, err := GetIntegerFromRequest(f, requestID, "id", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -40,7 +38,7 @@ func UpdateCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clie
 	if err != nil {
 		message := fmt.Sprintf("Could not load person [%d]", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 		return
 	}
 
@@ -48,7 +46,7 @@ func UpdateCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clie
 	if err != nil {
 		message := fmt.Sprintf("Person [%d] is not allowed to edit court", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyForbidden(requestID, responder, message)
 		return
 	}
 
@@ -56,16 +54,16 @@ func UpdateCourt(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clie
 	if err != nil {
 		message := fmt.Sprintf("problem updating court fields: courtID: %d", courtID)
 		DebugVerbose(f, requestID, message)
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyError(requestID, responder, err)
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		message := err.Error()
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
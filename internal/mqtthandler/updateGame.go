@@ -1,11 +1,10 @@
 package mqtthandler
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -18,14 +17,14 @@ var (
 	functionParseGamePosition = debug.NewFunction(pkg, "parseGamePosition")
 )
 
-// UpdateGame method
-func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+// UpdateGame method. With 'dryRun' the game is not changed, and the reply shows the result
+func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionUpdateGame
 	DebugVerbose(f, requestID, "")
 
 	userID, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
@@ -34,7 +33,7 @@ func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 	if err != nil {
 		message := fmt.Sprintf("Could not load person [%d]", userID)
 		DebugVerbose(f, requestID, err.Error())
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 		return
 	}
 
@@ -42,7 +41,7 @@ func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 	if err != nil {
 		message := fmt.Sprintf("Person [%d] is not allowed to edit game", userID)
 		DebugVerbose(f, requestID, err.Error())
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyForbidden(requestID, responder, message)
 		return
 	}
 
@@ -50,7 +49,31 @@ func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 	if err != nil {
 		message := "Problem parsing request data"
 		DebugVerbose(f, requestID, err.Error())
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyForbidden(requestID, responder, message)
+		return
+	}
+
+	dryRun, err := getDryRun(f, requestID, data)
+	if err != nil {
+		ReplyBadRequest(requestID, responder, err.Error())
+		return
+	}
+
+	if dryRun {
+		preview, err := model.DryRun(db, gameData.Court, func(ctx context.Context, tx model.Querier) error {
+			return model.UpdateGameTx(ctx, tx, gameData)
+		})
+		if err != nil {
+			DebugVerbose(f, requestID, err.Error())
+			if statusOf(err) != StatusInternalServerError {
+				ReplyError(requestID, responder, err)
+				return
+			}
+			ReplyForbidden(requestID, responder, "problem updating Game")
+			return
+		}
+
+		Reply(requestID, responder, dryRunReply(preview, nil))
 		return
 	}
 
@@ -58,18 +81,22 @@ func UpdateGame(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Clien
 	if err != nil {
 		message := "problem updating Game"
 		DebugVerbose(f, requestID, err.Error())
-		ReplyForbidden(requestID, client, replyTopic, message)
+		if statusOf(err) != StatusInternalServerError {
+			ReplyError(requestID, responder, err)
+			return
+		}
+		ReplyForbidden(requestID, responder, message)
 		return
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		message := "problem updating publications"
 		DebugVerbose(f, requestID, err.Error())
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
 
 // Parse the request data
@@ -123,7 +150,8 @@ func parseGameData(requestID int, data *map[string]interface{}) (*model.GameData
 			DebugVerbose(f, requestID, "key: %s, value: %#v", key, value)
 		}
 
-		index, err := parseGamePositionIndex(requestID, &itemMap, 0, model.NumberOfCourtPositions)
+		// the capacity of the court is checked against the index when the game is updated
+		index, err := parseGamePositionIndex(requestID, &itemMap, 0, model.DoublesCapacity)
 		if err != nil {
 			return nil, err
 		}
@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/publisher"
@@ -17,19 +15,19 @@ var (
 )
 
 // UpdatePerson method
-func UpdatePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Client, replyTopic string, data *map[string]interface{}) {
+func UpdatePerson(db *sql.DB, cfg *config.Config, requestID int, responder Responder, data *map[string]interface{}) {
 	f := functionUpdatePerson
 	DebugVerbose(f, requestID, "")
 
 	userID, err := checkAuthenticated(requestID, data)
 	if err != nil {
-		ReplyUnAuthorised(requestID, client, replyTopic, err.Error())
+		ReplyUnAuthorised(requestID, responder, err.Error())
 		return
 	}
 
 	personID, err := GetIntegerFromRequest(f, requestID, "id", data)
 	if err != nil {
-		ReplyBadRequest(requestID, client, replyTopic, err.Error())
+		ReplyBadRequest(requestID, responder, err.Error())
 		return
 	}
 
@@ -40,7 +38,7 @@ func UpdatePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	if err != nil {
 		message := fmt.Sprintf("Could not load person [%d]", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
 	if userID == personID {
@@ -48,14 +46,14 @@ func UpdatePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 		if err != nil {
 			message := "Not allowed to edit self"
 			DebugVerbose(f, requestID, message)
-			ReplyForbidden(requestID, client, replyTopic, message)
+			ReplyForbidden(requestID, responder, message)
 		}
 	} else {
 		err = user.CanEditOtherPeople()
 		if err != nil {
 			message := "Not allowed to edit other people"
 			DebugVerbose(f, requestID, message)
-			ReplyForbidden(requestID, client, replyTopic, message)
+			ReplyForbidden(requestID, responder, message)
 		}
 	}
 
@@ -63,15 +61,15 @@ func UpdatePerson(db *sql.DB, cfg *config.Config, requestID int, client mqtt.Cli
 	if err != nil {
 		message := fmt.Sprintf("problem updating person fields: userID: %d", userID)
 		DebugVerbose(f, requestID, message)
-		ReplyForbidden(requestID, client, replyTopic, message)
+		ReplyForbidden(requestID, responder, message)
 	}
 
-	err = publisher.UpdatePublications(db, client, cfg)
+	err = publisher.UpdatePublications(db, cfg)
 	if err != nil {
 		message := err.Error()
 		DebugVerbose(f, requestID, message)
-		ReplyInternalServerError(requestID, client, replyTopic, message)
+		ReplyInternalServerError(requestID, responder, message)
 	}
 
-	ReplyOK(requestID, client, replyTopic)
+	ReplyOK(requestID, responder)
 }
@@ -3,10 +3,14 @@ package mqtthandler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/rsmaxwell/players-tt-api/internal/basic"
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 )
@@ -14,22 +18,52 @@ import (
 var (
 	pkg                        = debug.NewPackage("mqtthandler")
 	functionGetStringField     = debug.NewFunction(pkg, "GetStringField")
+	functionGetReplyTopic      = debug.NewFunction(pkg, "GetReplyTopic")
 	functionGetData            = debug.NewFunction(pkg, "GetData")
 	functionPublish            = debug.NewFunction(pkg, "Publish")
 	functionSubscribe          = debug.NewFunction(pkg, "Subscribe")
 	functionCheckAuthenticated = debug.NewFunction(pkg, "checkAuthenticated")
 )
 
+const (
+	// ReplyTopicPrefix is the first level of the topics the replies to mqtt requests are published on
+	ReplyTopicPrefix = "reply"
+)
+
 type Request map[string]interface{}
 
-type Handler func(*sql.DB, *config.Config, int, mqtt.Client, string, *map[string]interface{})
+type Handler func(*sql.DB, *config.Config, int, Responder, *map[string]interface{})
+
+var requestCounter int64
+
+// NextRequestID returns a new request ID. Requests arrive over both mqtt and http, so the counter is atomic
+func NextRequestID() int {
+	return int(atomic.AddInt64(&requestCounter, 1))
+}
 
 func GetCommand(requestID int, request Request) (string, error) {
 	return GetStringField(requestID, request, "command")
 }
 
+// GetReplyTopic returns the topic to reply on, which must be 'reply/{clientid}'. The server publishes
+// the reply with its own rights, so any other topic is refused. The broker only lets a client read
+// the reply topic of its own client id
 func GetReplyTopic(requestID int, request Request) (string, error) {
-	return GetStringField(requestID, request, "replyTopic")
+	f := functionGetReplyTopic
+
+	replyTopic, err := GetStringField(requestID, request, "replyTopic")
+	if err != nil {
+		return "", err
+	}
+
+	levels := strings.Split(replyTopic, "/")
+	if len(levels) != 2 || levels[0] != ReplyTopicPrefix || levels[1] == "" || strings.ContainsAny(levels[1], "+#") {
+		message := fmt.Sprintf("the reply topic '%s' is not '%s/{clientid}'", replyTopic, ReplyTopicPrefix)
+		DebugVerbose(f, requestID, message)
+		return "", fmt.Errorf(message)
+	}
+
+	return replyTopic, nil
 }
 
 func GetStringField(requestID int, request Request, field string) (string, error) {
@@ -53,6 +87,14 @@ func GetStringField(requestID int, request Request, field string) (string, error
 	return replyTopic, nil
 }
 
+// GetOptionalStringField returns the field, or an empty string if the request does not contain it
+func GetOptionalStringField(requestID int, request Request, field string) (string, error) {
+	if _, ok := request[field]; !ok {
+		return "", nil
+	}
+	return GetStringField(requestID, request, field)
+}
+
 func GetData(request Request) (*map[string]interface{}, error) {
 	f := functionGetData
 
@@ -103,17 +145,32 @@ func Subscribe(requestID int, client mqtt.Client, topic string, callback mqtt.Me
 	DebugVerbose(f, requestID, "Subscribed to topic [%s]", topic)
 }
 
-func PublishResponse(requestID int, client mqtt.Client, topic string, status int, message string) {
+func ReplyStatus(requestID int, responder Responder, status int, message string) {
+	responder.Respond(requestID, statusReply(status, message))
+}
+
+// ReplyError replies with the status carried by a codeerror, or an internal server error otherwise
+func ReplyError(requestID int, responder Responder, err error) {
+	ReplyStatus(requestID, responder, statusOf(err), err.Error())
+}
 
-	reply := struct {
+func statusReply(status int, message string) interface{} {
+	return struct {
 		Status  int    `json:"status"`
 		Message string `json:"message"`
 	}{
 		Status:  status,
 		Message: message,
 	}
+}
 
-	Publish(requestID, client, topic, reply)
+// statusOf returns the status carried by a codeerror, or an internal server error otherwise
+func statusOf(err error) int {
+	var codeError *codeerror.CodeError
+	if errors.As(err, &codeError) {
+		return codeError.Status()
+	}
+	return StatusInternalServerError
 }
 
 func GetFormattedRequestID(requestID int) string {
@@ -149,31 +206,33 @@ const (
 	StatusBadRequest          = 400
 	StatusUnAuthorised        = 401
 	StatusForbidden           = 403
+	StatusNotFound            = 404
+	StatusFailedDependency    = 424
 	StatusInternalServerError = 500
 )
 
-func Reply(requestID int, client mqtt.Client, topic string, data interface{}) {
-	Publish(requestID, client, topic, data)
+func Reply(requestID int, responder Responder, data interface{}) {
+	responder.Respond(requestID, data)
 }
 
-func ReplyOK(requestID int, client mqtt.Client, topic string) {
-	PublishResponse(requestID, client, topic, StatusOK, "ok")
+func ReplyOK(requestID int, responder Responder) {
+	ReplyStatus(requestID, responder, StatusOK, "ok")
 }
 
-func ReplyBadRequest(requestID int, client mqtt.Client, topic string, message string) {
-	PublishResponse(requestID, client, topic, StatusBadRequest, message)
+func ReplyBadRequest(requestID int, responder Responder, message string) {
+	ReplyStatus(requestID, responder, StatusBadRequest, message)
 }
 
-func ReplyForbidden(requestID int, client mqtt.Client, topic string, message string) {
-	PublishResponse(requestID, client, topic, StatusForbidden, message)
+func ReplyForbidden(requestID int, responder Responder, message string) {
+	ReplyStatus(requestID, responder, StatusForbidden, message)
 }
 
-func ReplyUnAuthorised(requestID int, client mqtt.Client, topic string, message string) {
-	PublishResponse(requestID, client, topic, StatusUnAuthorised, message)
+func ReplyUnAuthorised(requestID int, responder Responder, message string) {
+	ReplyStatus(requestID, responder, StatusUnAuthorised, message)
 }
 
-func ReplyInternalServerError(requestID int, client mqtt.Client, topic string, message string) {
-	PublishResponse(requestID, client, topic, StatusInternalServerError, message)
+func ReplyInternalServerError(requestID int, responder Responder, message string) {
+	ReplyStatus(requestID, responder, StatusInternalServerError, message)
 }
 
 // checkAuthenticated method
@@ -228,6 +287,45 @@ func GetIntegerFromRequest(f *debug.Function, requestID int, key string, data *m
 	return int(value), nil
 }
 
+// GetOptionalIntegerFromRequest returns the integer, or the default value if the request does not contain the key
+func GetOptionalIntegerFromRequest(f *debug.Function, requestID int, key string, data *map[string]interface{}, def int) (int, error) {
+	if _, ok := (*data)[key]; !ok {
+		return def, nil
+	}
+	return GetIntegerFromRequest(f, requestID, key, data)
+}
+
+// GetOptionalStringFromRequest returns the string, or the default value if the request does not contain the key
+func GetOptionalStringFromRequest(f *debug.Function, requestID int, key string, data *map[string]interface{}, def string) (string, error) {
+	if _, ok := (*data)[key]; !ok {
+		return def, nil
+	}
+	return GetStringFromRequest(f, requestID, key, data)
+}
+
+func GetBoolFromRequest(f *debug.Function, requestID int, key string, data *map[string]interface{}) (bool, error) {
+
+	object, ok := (*data)[key]
+	if !ok {
+		return false, fmt.Errorf("could not find the key [%s]", key)
+	}
+	value, ok := object.(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected type for the key [%s]: %#v", key, object)
+	}
+
+	DebugVerbose(f, requestID, "key: %s, value: %t", key, value)
+	return value, nil
+}
+
+// GetOptionalBoolFromRequest returns the boolean, or the default value if the request does not contain the key
+func GetOptionalBoolFromRequest(f *debug.Function, requestID int, key string, data *map[string]interface{}, def bool) (bool, error) {
+	if _, ok := (*data)[key]; !ok {
+		return def, nil
+	}
+	return GetBoolFromRequest(f, requestID, key, data)
+}
+
 func Dump(f *debug.Function, requestID int, format string, a ...interface{}) *debug.Dump {
 	d := f.Dump(format, a...)
 	d.AddString("RequestID", GetFormattedRequestID(requestID))
@@ -0,0 +1,32 @@
+package mqtthandler
+
+import (
+	"testing"
+)
+
+func TestGetReplyTopic(t *testing.T) {
+
+	cases := []struct {
+		topic   string
+		allowed bool
+	}{
+		{"reply/c1", true},
+		{"reply/", false},
+		{"reply/+", false},
+		{"reply/#", false},
+		{"reply/c1/more", false},
+		{"user/5/notifications", false},
+		{"user/5/call", false},
+		{"getCourts", false},
+		{"request", false},
+	}
+
+	for _, c := range cases {
+		request := Request{"replyTopic": c.topic}
+		_, err := GetReplyTopic(0, request)
+		if (err == nil) != c.allowed {
+			t.Logf("Unexpected check of the reply topic '%s': expected %t, got %v", c.topic, c.allowed, err)
+			t.FailNow()
+		}
+	}
+}
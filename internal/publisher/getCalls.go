@@ -0,0 +1,40 @@
+package publisher
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionGetCalls = debug.NewFunction(pkg, "GetCalls")
+)
+
+// CallTopic returns the private topic of a person's call
+func CallTopic(personID int) string {
+	return fmt.Sprintf("%s/%d/call", UserTopicPrefix, personID)
+}
+
+// GetCalls method publishes each call on 'user/{personID}/call', which only the person called can
+// read. The topic is cleared when the call is accepted or runs out
+func GetCalls(db *sql.DB, cfg *config.Config) ([]Entry, error) {
+	f := functionGetCalls
+	f.DebugVerbose("")
+
+	calls, err := model.ListCalls(db, cfg.CallTimeout)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return nil, err
+	}
+
+	array := []Entry{}
+	for _, call := range calls {
+		topic := CallTopic(call.PersonId.ID)
+		array = append(array, Entry{topic: topic, object: call})
+	}
+
+	return array, nil
+}
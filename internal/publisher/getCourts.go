@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -15,7 +14,7 @@ var (
 )
 
 // GetCourts method
-func GetCourts(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, error) {
+func GetCourts(db *sql.DB, cfg *config.Config) ([]Entry, error) {
 	f := functionGetCourts
 	f.DebugVerbose("")
 
@@ -28,14 +27,8 @@ func GetCourts(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, err
 	entry := Entry{topic: "getCourts", object: listOfCourts}
 	array := []Entry{entry}
 
+	// the listed courts already hold their capacity and positions
 	for _, court := range listOfCourts {
-		court := model.Court{ID: court.ID}
-		err := court.LoadCourt(db)
-		if err != nil {
-			f.DebugVerbose(err.Error())
-			return nil, err
-		}
-
 		topic := fmt.Sprintf("getCourt/%d", court.ID)
 		entry = Entry{topic: topic, object: court}
 		array = append(array, entry)
@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"database/sql"
+
+	"github.com/rsmaxwell/players-tt-api/internal/config"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+var (
+	functionGetLeaderboard = debug.NewFunction(pkg, "GetLeaderboard")
+)
+
+// GetLeaderboard method
+func GetLeaderboard(db *sql.DB, cfg *config.Config) ([]Entry, error) {
+	f := functionGetLeaderboard
+	f.DebugVerbose("")
+
+	list, err := model.ListLeaderboard(db)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return nil, err
+	}
+
+	entry := Entry{topic: "getLeaderboard", object: list}
+	array := []Entry{entry}
+	return array, nil
+}
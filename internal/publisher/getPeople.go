@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -25,20 +24,20 @@ func init() {
 }
 
 // GetPeople method
-func GetPeople(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, error) {
+func GetPeople(db *sql.DB, cfg *config.Config) ([]Entry, error) {
 	f := functionGetPeople
 	f.DebugVerbose("")
 
 	array := []Entry{}
 
-	items, err := getPeopleAll(db, client, cfg)
+	items, err := getPeopleAll(db, cfg)
 	if err != nil {
 		return nil, err
 	}
 	array = append(array, items...)
 
 	for filterName, whereClause := range filters {
-		items, err = getPeopleFilter(db, client, cfg, filterName, whereClause)
+		items, err = getPeopleFilter(db, cfg, filterName, whereClause)
 		if err != nil {
 			return nil, err
 		}
@@ -48,7 +47,7 @@ func GetPeople(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, err
 	return array, nil
 }
 
-func getPeopleAll(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, error) {
+func getPeopleAll(db *sql.DB, cfg *config.Config) ([]Entry, error) {
 	f := functionGetPeopleAll
 	f.DebugVerbose("")
 
@@ -79,7 +78,7 @@ func getPeopleAll(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry,
 	return array, nil
 }
 
-func getPeopleFilter(db *sql.DB, client mqtt.Client, cfg *config.Config, filterName string, whereClause string) ([]Entry, error) {
+func getPeopleFilter(db *sql.DB, cfg *config.Config, filterName string, whereClause string) ([]Entry, error) {
 	f := functionGetPeopleFilter
 	f.DebugVerbose("filterName: [%s], whereClause: [%s]", filterName, whereClause)
 
@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/model"
@@ -16,7 +14,7 @@ var (
 )
 
 // GetWaiters method
-func GetWaiters(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, error) {
+func GetWaiters(db *sql.DB, cfg *config.Config) ([]Entry, error) {
 	f := functionGetWaiters
 	f.DebugVerbose("")
 
@@ -40,6 +38,8 @@ func GetWaiters(db *sql.DB, client mqtt.Client, cfg *config.Config) ([]Entry, er
 		w.PersonId.ID = p.ID
 		w.PersonId.Knownas = p.Knownas
 		w.Start = waiter.Start.Unix()
+		w.Paused = waiter.Paused
+		w.Priority = waiter.Priority
 
 		listOfWaiters = append(listOfWaiters, w)
 	}
@@ -0,0 +1,120 @@
+package publisher
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	listenerBufferSize = 256
+)
+
+// Message is a publication sent to a listener
+type Message struct {
+	Topic   string `json:"topic"`
+	Message string `json:"message"`
+}
+
+// Listener receives the publications whose topic matches one of its filters, and which the person
+// listening may read
+type Listener struct {
+	filters  []string
+	personID int
+	C        chan Message
+}
+
+var (
+	listeners = map[*Listener]bool{}
+)
+
+// Listen registers a listener for the topics matching the filters, which use the mqtt wildcards '+'
+// and '#'. The current publications matching the filters are returned, and every later change is
+// sent on the listener's channel. The channel is closed if the listener falls too far behind. The
+// person's private topics are included, but nobody else's; a person id of 0 gets only public topics
+func Listen(filters []string, personID int) (*Listener, []Message) {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	listener := &Listener{filters: filters, personID: personID, C: make(chan Message, listenerBufferSize)}
+	listeners[listener] = true
+
+	topics := []string{}
+	for topic := range previous {
+		if listener.matches(topic) {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+
+	snapshot := []Message{}
+	for _, topic := range topics {
+		snapshot = append(snapshot, Message{Topic: topic, Message: previous[topic]})
+	}
+
+	return listener, snapshot
+}
+
+// Close removes the listener
+func (listener *Listener) Close() {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	removeListener(listener)
+}
+
+func removeListener(listener *Listener) {
+	if listeners[listener] {
+		delete(listeners, listener)
+		close(listener.C)
+	}
+}
+
+func (listener *Listener) matches(topic string) bool {
+	if !CanRead(listener.personID, topic) {
+		return false
+	}
+	for _, filter := range listener.filters {
+		if TopicMatches(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyListeners sends a publication to the matching listeners. The mutex must be held
+func notifyListeners(topic string, message string) {
+	for listener := range listeners {
+		if !listener.matches(topic) {
+			continue
+		}
+
+		select {
+		case listener.C <- Message{Topic: topic, Message: message}:
+		default:
+			removeListener(listener)
+		}
+	}
+}
+
+// TopicMatches reports whether the topic matches the filter, using the mqtt wildcards
+func TopicMatches(filter string, topic string) bool {
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
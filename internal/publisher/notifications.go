@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+	"github.com/rsmaxwell/players-tt-api/model"
+)
+
+const (
+	// UserTopicPrefix is the first level of the topics which are private to one person
+	UserTopicPrefix = "user"
+)
+
+var (
+	functionPublishNotifications = debug.NewFunction(pkg, "publishNotifications")
+)
+
+// NotificationTopic returns the private topic of a person's notifications
+func NotificationTopic(personID int) string {
+	return fmt.Sprintf("%s/%d/notifications", UserTopicPrefix, personID)
+}
+
+// CanRead reports whether a person may receive the messages on a topic, or subscribe with a topic
+// filter. The topics under 'user/{id}' are private to that person, and every other topic is public. A
+// person id of 0 is someone who has not signed in. A filter with a wildcard in place of the id is
+// refused, and a filter such as '#' is allowed, as each message it matches is checked on its own
+func CanRead(personID int, topic string) bool {
+
+	levels := strings.Split(topic, "/")
+	if levels[0] != UserTopicPrefix {
+		return true
+	}
+
+	return personID > 0 && len(levels) > 1 && levels[1] == strconv.Itoa(personID)
+}
+
+// publishNotifications works out which waiters have moved in the queue, then publishes every new
+// notification on its person's topic. The notifications are not retained: a person who missed one
+// asks for it with 'getNotifications'. The mutex must be held
+func publishNotifications(db *sql.DB) error {
+	f := functionPublishNotifications
+
+	err := model.NotifyQueuePositions(db)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return err
+	}
+
+	notifications, err := model.ListNewNotifications(db)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return err
+	}
+
+	for _, notification := range notifications {
+		bytes, err := json.Marshal(notification)
+		if err != nil {
+			f.DebugVerbose(err.Error())
+			return err
+		}
+
+		topic := NotificationTopic(notification.Person)
+		message := string(bytes)
+
+		publish(topic, message, false)
+		notifyListeners(topic, message)
+	}
+
+	return nil
+}
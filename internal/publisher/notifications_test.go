@@ -0,0 +1,39 @@
+package publisher
+
+import (
+	"testing"
+)
+
+func TestCanRead(t *testing.T) {
+
+	cases := []struct {
+		personID int
+		topic    string
+		allowed  bool
+	}{
+		{0, "getCourts", true},
+		{0, "#", true},
+		{0, "user/5/notifications", false},
+		{5, "user/5/notifications", true},
+		{6, "user/5/notifications", false},
+		{5, "user/+/notifications", false},
+		{5, "user/#", false},
+		{5, "user", false},
+		{5, CallTopic(5), true},
+		{6, CallTopic(5), false},
+	}
+
+	for _, c := range cases {
+		allowed := CanRead(c.personID, c.topic)
+		if allowed != c.allowed {
+			t.Logf("Unexpected access for person [%d] to '%s': expected %t, got %t", c.personID, c.topic, c.allowed, allowed)
+			t.FailNow()
+		}
+	}
+
+	listener := &Listener{filters: []string{"#"}, personID: 5}
+	if !listener.matches(NotificationTopic(5)) || listener.matches(NotificationTopic(6)) {
+		t.Log("The listener matched the wrong private topics")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,171 @@
+package publisher
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation
+type Operation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON writes the operation, leaving out the value of a 'remove' operation
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{Op: o.Op, Path: o.Path})
+	}
+
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{Op: o.Op, Path: o.Path, Value: o.Value})
+}
+
+// Patch is published on the patch topic. Applying it to the snapshot of the previous sequence number
+// gives the current snapshot. A client which misses a sequence number resyncs from the retained snapshot
+type Patch struct {
+	Seq   int         `json:"seq"`
+	Patch []Operation `json:"patch"`
+}
+
+// Diff returns the operations which turn the old json document into the new one. Both documents are
+// in the form given by json.Unmarshal into an interface{}
+func Diff(oldDoc interface{}, newDoc interface{}) []Operation {
+	return diff("", oldDoc, newDoc, []Operation{})
+}
+
+func diff(path string, oldDoc interface{}, newDoc interface{}, ops []Operation) []Operation {
+
+	oldObject, ok1 := oldDoc.(map[string]interface{})
+	newObject, ok2 := newDoc.(map[string]interface{})
+	if ok1 && ok2 {
+		return diffObjects(path, oldObject, newObject, ops)
+	}
+
+	oldArray, ok1 := oldDoc.([]interface{})
+	newArray, ok2 := newDoc.([]interface{})
+	if ok1 && ok2 {
+		return diffArrays(path, oldArray, newArray, ops)
+	}
+
+	if reflect.DeepEqual(oldDoc, newDoc) {
+		return ops
+	}
+
+	return append(ops, Operation{Op: "replace", Path: path, Value: newDoc})
+}
+
+func diffObjects(path string, oldObject map[string]interface{}, newObject map[string]interface{}, ops []Operation) []Operation {
+
+	keys := []string{}
+	for key := range oldObject {
+		keys = append(keys, key)
+	}
+	for key := range newObject {
+		if _, ok := oldObject[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "/" + escapePointer(key)
+
+		oldValue, inOld := oldObject[key]
+		newValue, inNew := newObject[key]
+
+		if !inNew {
+			ops = append(ops, Operation{Op: "remove", Path: childPath})
+		} else if !inOld {
+			ops = append(ops, Operation{Op: "add", Path: childPath, Value: newValue})
+		} else {
+			ops = diff(childPath, oldValue, newValue, ops)
+		}
+	}
+
+	return ops
+}
+
+// diffArrays finds the longest common subsequence of the elements, then removes and adds the rest.
+// Where an element is removed and another added at the same place, the two are diffed instead, so a
+// change to one field of a person is a single 'replace'
+func diffArrays(path string, oldArray []interface{}, newArray []interface{}, ops []Operation) []Operation {
+
+	n := len(oldArray)
+	m := len(newArray)
+
+	// lengths[i][j] is the length of the longest common subsequence of oldArray[i:] and newArray[j:]
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if reflect.DeepEqual(oldArray[i], newArray[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	// 'index' is the position in the array as it is after the operations so far
+	index := 0
+	removed := []interface{}{}
+	added := []interface{}{}
+
+	flush := func() {
+		for len(removed) > 0 && len(added) > 0 {
+			ops = diff(path+"/"+strconv.Itoa(index), removed[0], added[0], ops)
+			removed = removed[1:]
+			added = added[1:]
+			index++
+		}
+		for range removed {
+			ops = append(ops, Operation{Op: "remove", Path: path + "/" + strconv.Itoa(index)})
+		}
+		for _, value := range added {
+			ops = append(ops, Operation{Op: "add", Path: path + "/" + strconv.Itoa(index), Value: value})
+			index++
+		}
+		removed = []interface{}{}
+		added = []interface{}{}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		if i < n && j < m && reflect.DeepEqual(oldArray[i], newArray[j]) {
+			flush()
+			index++
+			i++
+			j++
+		} else if j >= m || (i < n && lengths[i+1][j] >= lengths[i][j+1]) {
+			removed = append(removed, oldArray[i])
+			i++
+		} else {
+			added = append(added, newArray[j])
+			j++
+		}
+	}
+	flush()
+
+	return ops
+}
+
+// escapePointer escapes a key for use in a JSON Pointer, as described in RFC 6901
+func escapePointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
@@ -0,0 +1,129 @@
+package publisher
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+
+	cases := []struct {
+		old string
+		new string
+	}{
+		{`[]`, `[{"id":1,"knownas":"Al"}]`},
+		{`[{"id":1,"knownas":"Al"},{"id":2,"knownas":"Bo"}]`, `[{"id":2,"knownas":"Bo"}]`},
+		{`[{"id":1,"knownas":"Al"},{"id":2,"knownas":"Bo"}]`, `[{"id":1,"knownas":"Alf"},{"id":2,"knownas":"Bo"}]`},
+		{`[1,2,3,4,5]`, `[2,9,3,5,6,7]`},
+		{`{"a":1,"b/c":2,"d":[1]}`, `{"a":1,"e~f":3,"d":[1,2]}`},
+		{`{"a":{"b":null}}`, `{"a":{"b":1}}`},
+	}
+
+	for _, c := range cases {
+		var oldDoc, newDoc interface{}
+		json.Unmarshal([]byte(c.old), &oldDoc)
+		json.Unmarshal([]byte(c.new), &newDoc)
+
+		ops := Diff(oldDoc, newDoc)
+
+		result, err := apply(oldDoc, ops)
+		if err != nil {
+			t.Logf("Could not apply the patch to %s: %s", c.old, err)
+			t.FailNow()
+		}
+
+		if !reflect.DeepEqual(result, newDoc) {
+			bytes, _ := json.Marshal(ops)
+			t.Logf("Unexpected result: %s --> %s, patch: %s", c.old, c.new, string(bytes))
+			t.FailNow()
+		}
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+
+	var doc interface{}
+	json.Unmarshal([]byte(`[{"id":1,"courts":[1,2]}]`), &doc)
+
+	ops := Diff(doc, doc)
+	if len(ops) != 0 {
+		t.Logf("Unexpected operations: %#v", ops)
+		t.FailNow()
+	}
+}
+
+// apply is a minimal implementation of the 'add', 'remove' and 'replace' operations
+func apply(doc interface{}, ops []Operation) (interface{}, error) {
+
+	for _, op := range ops {
+		var err error
+		doc, err = applyOne(doc, splitPointer(op.Path), op)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func applyOne(doc interface{}, tokens []string, op Operation) (interface{}, error) {
+
+	if len(tokens) == 0 {
+		return op.Value, nil
+	}
+
+	token := tokens[0]
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		if len(tokens) > 1 {
+			value, err := applyOne(container[token], tokens[1:], op)
+			container[token] = value
+			return container, err
+		}
+		if op.Op == "remove" {
+			delete(container, token)
+		} else {
+			container[token] = op.Value
+		}
+		return container, nil
+
+	case []interface{}:
+		index, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) > 1 {
+			value, err := applyOne(container[index], tokens[1:], op)
+			container[index] = value
+			return container, err
+		}
+		switch op.Op {
+		case "remove":
+			return append(container[:index:index], container[index+1:]...), nil
+		case "add":
+			result := append([]interface{}{}, container[:index]...)
+			result = append(result, op.Value)
+			return append(result, container[index:]...), nil
+		default:
+			container[index] = op.Value
+			return container, nil
+		}
+	}
+
+	return doc, nil
+}
+
+func splitPointer(path string) []string {
+	if path == "" {
+		return []string{}
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens
+}
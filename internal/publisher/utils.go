@@ -3,17 +3,20 @@ package publisher
 import (
 	"database/sql"
 	"encoding/json"
+	"sync"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 	"github.com/rsmaxwell/players-tt-api/internal/utils"
+	"github.com/rsmaxwell/players-tt-api/model"
 )
 
 var (
 	pkg = debug.NewPackage("publisher")
 
 	functionUpdatePublications = debug.NewFunction(pkg, "UpdatePublications")
+	functionRepublishAll       = debug.NewFunction(pkg, "RepublishAll")
 )
 
 type Entry struct {
@@ -21,27 +24,65 @@ type Entry struct {
 	object interface{}
 }
 
-type Handler func(*sql.DB, mqtt.Client, *config.Config) ([]Entry, error)
+type Handler func(*sql.DB, *config.Config) ([]Entry, error)
 
 var (
 	handlers = []Handler{
 		GetCourts,
 		GetPeople,
 		GetWaiters,
+		GetLeaderboard,
+		GetCalls,
 	}
 
 	previous = map[string]string{}
+
+	// sequence numbers of the patches published for each topic
+	sequences = map[string]int{}
+
+	// requests are handled over both mqtt and http, so updates to the publications are serialised
+	mutex sync.Mutex
+
+	client mqtt.Client
 )
 
+// SetClient sets the mqtt client the publications are sent through
+func SetClient(c mqtt.Client) {
+	client = c
+}
+
 // UpdatePublications method
-func UpdatePublications(db *sql.DB, client mqtt.Client, cfg *config.Config) error {
+func UpdatePublications(db *sql.DB, cfg *config.Config) error {
 	f := functionUpdatePublications
 	f.DebugVerbose("")
 
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return updatePublications(db, cfg, false)
+}
+
+// RepublishAll publishes every topic again, whether or not it has changed. It is used when the
+// broker may have lost its retained messages, for example after it restarts without persistence
+func RepublishAll(db *sql.DB, cfg *config.Config) error {
+	f := functionRepublishAll
+	f.DebugVerbose("")
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return updatePublications(db, cfg, true)
+}
+
+// updatePublications publishes the topics which have changed, or every topic when forced. The mutex
+// must be held
+func updatePublications(db *sql.DB, cfg *config.Config, force bool) error {
+	f := functionUpdatePublications
+
 	history := map[string]string{}
 
 	for _, handler := range handlers {
-		entries, err := handler(db, client, cfg)
+		entries, err := handler(db, cfg)
 		if err != nil {
 			return err
 		}
@@ -56,15 +97,88 @@ func UpdatePublications(db *sql.DB, client mqtt.Client, cfg *config.Config) erro
 			history[entry.topic] = newMessage
 
 			oldMessage, ok := previous[entry.topic]
-			if ok && (oldMessage == newMessage) {
+			changed := !ok || (oldMessage != newMessage)
+			if !changed && !force {
 				continue
 			}
 
-			utils.Publish(client, entry.topic, newMessage)
+			publish(entry.topic, newMessage, true)
+			notifyListeners(entry.topic, newMessage)
+
+			if ok && changed && wantsPatch(cfg, entry.topic) {
+				err = publishPatch(entry.topic, oldMessage, newMessage)
+				if err != nil {
+					f.DebugVerbose(err.Error())
+					return err
+				}
+			}
+		}
+	}
+
+	// topics which are no longer published, such as those of deleted courts and people, are cleared
+	// from the broker with an empty retained message
+	for topic := range previous {
+		if _, ok := history[topic]; !ok {
+			publish(topic, "", true)
+			notifyListeners(topic, "")
+			delete(sequences, topic)
 		}
 	}
 
 	previous = history
 
+	return publishNotifications(db)
+}
+
+// publish sends a message to the broker and counts it in the metrics
+func publish(topic string, message string, retained bool) {
+	if retained {
+		utils.Publish(client, topic, message)
+	} else {
+		utils.PublishTransient(client, topic, message)
+	}
+	model.MetricsData.RecordPublish()
+}
+
+// wantsPatch reports whether the configuration asks for patches on the topic
+func wantsPatch(cfg *config.Config, topic string) bool {
+	for _, filter := range cfg.Publisher.Patches {
+		if TopicMatches(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// publishPatch publishes the changes from the old message to the new one on '{topic}/patch'. The
+// patches are not retained, as a late joiner starts from the retained snapshot
+func publishPatch(topic string, oldMessage string, newMessage string) error {
+
+	var oldDoc interface{}
+	err := json.Unmarshal([]byte(oldMessage), &oldDoc)
+	if err != nil {
+		return err
+	}
+
+	var newDoc interface{}
+	err = json.Unmarshal([]byte(newMessage), &newDoc)
+	if err != nil {
+		return err
+	}
+
+	sequences[topic]++
+	patch := Patch{Seq: sequences[topic], Patch: Diff(oldDoc, newDoc)}
+
+	bytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	patchTopic := topic + "/patch"
+	message := string(bytes)
+
+	publish(patchTopic, message, false)
+	notifyListeners(patchTopic, message)
+
 	return nil
 }
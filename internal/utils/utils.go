@@ -14,6 +14,7 @@ var (
 	functionGetIntegerFromMap = debug.NewFunction(pkg, "GetIntegerFromMap")
 	functionPublishObject     = debug.NewFunction(pkg, "PublishObject")
 	functionPublish           = debug.NewFunction(pkg, "Publish")
+	functionPublishTransient  = debug.NewFunction(pkg, "PublishTransient")
 	functionSubscribe         = debug.NewFunction(pkg, "Subscribe")
 )
 
@@ -71,6 +72,16 @@ func Publish(client mqtt.Client, topic string, message string) mqtt.Token {
 	return client.Publish(topic, qos, retained, message)
 }
 
+// PublishTransient publishes a message which is not retained by the broker
+func PublishTransient(client mqtt.Client, topic string, message string) mqtt.Token {
+	f := functionPublishTransient
+
+	f.DebugVerbose("Publish to [%s] message: %s", topic, message)
+	var qos byte = 1
+	var retained bool = false
+	return client.Publish(topic, qos, retained, message)
+}
+
 func Subscribe(client mqtt.Client, topic string, callback mqtt.MessageHandler) error {
 	f := functionSubscribe
 
@@ -0,0 +1,299 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Call type is the request for a person picked to fill a court to come and play. It lasts until they
+// accept it or leave the court. When calls have a time limit, the deadline is when it runs out
+type Call struct {
+	PersonId  PersonId   `json:"personId"`
+	Court     int        `json:"court"`
+	CourtName string     `json:"courtName"`
+	Position  int        `json:"position"`
+	Called    time.Time  `json:"called"`
+	Deadline  *time.Time `json:"deadline"`
+}
+
+const (
+	// CallTable is the name of the call table. It keeps the waiter's place in the queue, so that a
+	// person who does not answer can have it back
+	CallTable = "call"
+)
+
+var (
+	functionCallPlayerTx  = debug.NewFunction(pkg, "callPlayerTx")
+	functionAcceptCall    = debug.NewFunction(pkg, "AcceptCall")
+	functionAcceptCallTx  = debug.NewFunction(pkg, "AcceptCallTx")
+	functionListCalls     = debug.NewFunction(pkg, "ListCalls")
+	functionListCallsTx   = debug.NewFunction(pkg, "ListCallsTx")
+	functionExpireCalls   = debug.NewFunction(pkg, "ExpireCalls")
+	functionExpireCallsTx = debug.NewFunction(pkg, "ExpireCallsTx")
+)
+
+// CallDeadline returns when a call runs out, or nil if calls have no time limit
+func CallDeadline(called time.Time, timeout time.Duration) *time.Time {
+
+	if timeout <= 0 {
+		return nil
+	}
+
+	deadline := called.Add(timeout)
+	return &deadline
+}
+
+// callPlayerTx calls a waiter who has just been put on a court, and keeps the place they had in the queue
+func callPlayerTx(ctx context.Context, db Querier, waiter Waiter, court *Court, position int) error {
+	f := functionCallPlayerTx
+
+	fields := "person, court, position, called, start, place, priority"
+	values := "$1, $2, $3, CURRENT_TIMESTAMP, $4, $5, $6"
+	sqlStatement := "INSERT INTO " + CallTable + " (" + fields + ") VALUES (" + values + ")"
+
+	_, err := db.ExecContext(ctx, sqlStatement, waiter.Person, court.ID, position, waiter.Start, waiter.Place, waiter.Priority)
+	if err != nil {
+		message := "Could not insert into " + CallTable
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	return notifyTx(ctx, db, waiter.Person, NotificationCalled, fmt.Sprintf("You have been called to %s", court.Name))
+}
+
+// AcceptCall confirms a person has heard their call and is coming to the court
+func AcceptCall(db *sql.DB, personID int) (err error) {
+	f := functionAcceptCall
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = AcceptCallTx(ctx, tx, personID)
+	return err
+}
+
+// AcceptCallTx confirms a person has heard their call. The person stays on the court, and the call is gone
+func AcceptCallTx(ctx context.Context, db Querier, personID int) error {
+	f := functionAcceptCallTx
+
+	sqlStatement := "DELETE FROM " + CallTable + " WHERE person=$1"
+	result, err := db.ExecContext(ctx, sqlStatement, personID)
+	if err != nil {
+		message := "Could not delete the call"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		message := "Could not get the count of rows affected"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+	if count == 0 {
+		return codeerror.NewNotFound(fmt.Sprintf("person [%d] has not been called", personID))
+	}
+
+	return nil
+}
+
+// ListCalls returns the calls which have not been accepted
+func ListCalls(db *sql.DB, timeout time.Duration) (list []Call, err error) {
+	f := functionListCalls
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ListCallsTx(ctx, tx, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListCallsTx returns the calls which have not been accepted, oldest first, with their deadlines for the
+// given timeout
+func ListCallsTx(ctx context.Context, db Querier, timeout time.Duration) ([]Call, error) {
+	f := functionListCallsTx
+
+	sqlStatement := `
+		SELECT c.person, COALESCE(p.knownas, ''), c.court, COALESCE(k.name, ''), c.position, c.called
+		FROM ` + CallTable + ` c
+		LEFT JOIN ` + PersonTable + ` p ON p.id = c.person
+		LEFT JOIN ` + CourtTable + ` k ON k.id = c.court
+		ORDER BY c.called, c.court, c.position`
+
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the calls"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Call{}
+	for rows.Next() {
+
+		var call Call
+		err := rows.Scan(&call.PersonId.ID, &call.PersonId.Knownas, &call.Court, &call.CourtName, &call.Position, &call.Called)
+		if err != nil {
+			message := "Could not scan the call"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		call.Deadline = CallDeadline(call.Called, timeout)
+		list = append(list, call)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the calls"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ExpireCalls sends the people who did not answer their call in time back to the queue, and calls the next waiters
+func ExpireCalls(db *sql.DB, timeout time.Duration, keepPlace bool, strategy FillStrategy) (list []Call, err error) {
+	f := functionExpireCalls
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ExpireCallsTx(ctx, tx, timeout, keepPlace, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ExpireCallsTx sends the people who did not answer their call within the timeout back to the queue, and
+// returns their calls. With keepPlace they go back to the place they had, paused so they are not
+// called again at once, and resume when they arrive. Otherwise they join the back of the queue, and
+// are left out of the refill. Each court which lost a player is then filled again, which calls the
+// next waiters
+func ExpireCallsTx(ctx context.Context, db Querier, timeout time.Duration, keepPlace bool, strategy FillStrategy) ([]Call, error) {
+	f := functionExpireCallsTx
+
+	expired := []Call{}
+	if timeout <= 0 {
+		return expired, nil
+	}
+
+	calls, err := ListCallsTx(ctx, db, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, call := range calls {
+		if call.Deadline.After(now) {
+			continue
+		}
+
+		var start time.Time
+		var place, priority int
+		sqlStatement := "SELECT start, place, priority FROM " + CallTable + " WHERE person=$1"
+		err = db.QueryRowContext(ctx, sqlStatement, call.PersonId.ID).Scan(&start, &place, &priority)
+		if err != nil {
+			message := "Could not load the call"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return nil, err
+		}
+
+		// leaving the court also deletes the call
+		err = MakePlayerWaitTx(ctx, db, call.PersonId.ID)
+		if err != nil {
+			message := fmt.Sprintf("Could not send person [%d] back to the queue", call.PersonId.ID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		if keepPlace {
+			sqlStatement = "UPDATE " + WaitingTable + " SET start=$1, place=$2, priority=$3, paused=TRUE WHERE person=$4"
+			_, err = db.ExecContext(ctx, sqlStatement, start, place, priority, call.PersonId.ID)
+			if err != nil {
+				message := "Could not put the waiter back in their place"
+				f.Errorf(message)
+				f.DumpSQLError(err, message, sqlStatement)
+				return nil, err
+			}
+		} else {
+			// paused until the courts are filled again, so they are not called straight back
+			err = SetWaiterPausedTx(ctx, db, call.PersonId.ID, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		expired = append(expired, call)
+	}
+
+	courts := map[int]bool{}
+	for _, call := range expired {
+		courts[call.Court] = true
+	}
+
+	list := []int{}
+	for courtID := range courts {
+		list = append(list, courtID)
+	}
+	sort.Ints(list)
+
+	for _, courtID := range list {
+		_, _, err = FillCourtTx(ctx, db, courtID, strategy)
+		if err != nil {
+			message := fmt.Sprintf("Could not fill court [%d]", courtID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+	}
+
+	if !keepPlace {
+		for _, call := range expired {
+			err = SetWaiterPausedTx(ctx, db, call.PersonId.ID, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+func TestCallDeadline(t *testing.T) {
+
+	called := time.Date(2021, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	if CallDeadline(called, 0) != nil {
+		t.Log("Unexpected deadline without a timeout")
+		t.FailNow()
+	}
+
+	deadline := CallDeadline(called, 2*time.Minute)
+	if deadline == nil || !deadline.Equal(called.Add(2*time.Minute)) {
+		t.Logf("Unexpected deadline: %v", deadline)
+		t.FailNow()
+	}
+}
+
+func TestExpireCalls(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	_, selections, err := FillCourt(db, courtID, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(selections) < 2 {
+		t.Log("Not enough people were picked")
+		t.FailNow()
+	}
+
+	calls, err := ListCalls(db, time.Minute)
+	if err != nil {
+		t.Log("Could not list the calls")
+		t.FailNow()
+	}
+	if len(calls) != len(selections) {
+		t.Logf("Unexpected number of calls: %d, expected: %d", len(calls), len(selections))
+		t.FailNow()
+	}
+
+	accepted := selections[0].PersonId.ID
+	err = AcceptCall(db, accepted)
+	if err != nil {
+		t.Log("Could not accept the call")
+		t.FailNow()
+	}
+
+	err = AcceptCall(db, accepted)
+	if err == nil {
+		t.Log("Unexpected second accept of the call")
+		t.FailNow()
+	}
+
+	expired, err := ExpireCalls(db, time.Nanosecond, true, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not expire the calls")
+		t.FailNow()
+	}
+	if len(expired) != len(selections)-1 {
+		t.Logf("Unexpected number of expired calls: %d, expected: %d", len(expired), len(selections)-1)
+		t.FailNow()
+	}
+
+	for _, call := range expired {
+		waiters, err := ListWaitersForPerson(ctx, db, call.PersonId.ID)
+		if err != nil {
+			t.Log("Could not list the waiters")
+			t.FailNow()
+		}
+		if len(waiters) != 1 || !waiters[0].Paused {
+			t.Logf("Person [%d] is not back in the queue, paused", call.PersonId.ID)
+			t.FailNow()
+		}
+	}
+
+	players, err := ListPlayersForCourt(ctx, db, courtID)
+	if err != nil {
+		t.Log("Could not list the players")
+		t.FailNow()
+	}
+
+	found := false
+	for _, player := range players {
+		if player.Person == accepted {
+			found = true
+		}
+	}
+	if !found {
+		t.Logf("Person [%d] who accepted the call is not on court [%d]", accepted, courtID)
+		t.FailNow()
+	}
+}
+
+func TestExpireCallsMoveToBack(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	_, selections, err := FillCourt(db, courtID, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(selections) == 0 {
+		t.Log("Nobody was picked")
+		t.FailNow()
+	}
+
+	expired, err := ExpireCalls(db, time.Nanosecond, false, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not expire the calls")
+		t.FailNow()
+	}
+	if len(expired) != len(selections) {
+		t.Logf("Unexpected number of expired calls: %d, expected: %d", len(expired), len(selections))
+		t.FailNow()
+	}
+
+	players, err := GetPlayersForCourtAsMap(ctx, db, courtID)
+	if err != nil {
+		t.Log("Could not list the players")
+		t.FailNow()
+	}
+
+	for _, call := range expired {
+		waiters, err := ListWaitersForPerson(ctx, db, call.PersonId.ID)
+		if err != nil {
+			t.Log("Could not list the waiters")
+			t.FailNow()
+		}
+		if len(waiters) != 1 || waiters[0].Paused {
+			t.Logf("Person [%d] is not back in the queue, ready to play", call.PersonId.ID)
+			t.FailNow()
+		}
+
+		player, ok := players[call.Position]
+		if !ok {
+			t.Logf("Position [%d] on court [%d] was not filled again", call.Position, courtID)
+			t.FailNow()
+		}
+		if player.Person == call.PersonId.ID {
+			t.Logf("Person [%d] was called again to court [%d]", call.PersonId.ID, courtID)
+			t.FailNow()
+		}
+	}
+}
@@ -2,7 +2,6 @@ package model
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
@@ -11,9 +10,10 @@ import (
 var (
 	functionCheckConistency       = debug.NewFunction(pkg, "CheckConistency")
 	functionCheckConistencyPerson = debug.NewFunction(pkg, "CheckConistencyPerson")
+	functionCheckConistencyCourts = debug.NewFunction(pkg, "CheckConistencyCourts")
 )
 
-func CheckConistency(ctx context.Context, db *sql.DB, fix bool) (int, error) {
+func CheckConistency(ctx context.Context, db Querier, fix bool) (int, error) {
 	f := functionCheckConistency
 
 	list, err := ListPeopleTx(ctx, db, "")
@@ -39,10 +39,80 @@ func CheckConistency(ctx context.Context, db *sql.DB, fix bool) (int, error) {
 		total = total + count
 	}
 
+	count, err := CheckConistencyCourts(ctx, db, fix)
+	if err != nil {
+		message := "Problem checking consistany for the courts"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+
+	total = total + count
+
 	return total, nil
 }
 
-func (person *FullPerson) CheckConistencyPerson(ctx context.Context, db *sql.DB, fix bool) (int, error) {
+// CheckConistencyCourts looks for players in a position beyond the capacity of their court
+func CheckConistencyCourts(ctx context.Context, db Querier, fix bool) (int, error) {
+	f := functionCheckConistencyCourts
+
+	sqlStatement := `
+		SELECT p.person, p.court, p.position, c.capacity
+		FROM ` + PlayingTable + ` p JOIN ` + CourtTable + ` c ON c.id = p.court
+		WHERE p.position >= c.capacity`
+
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the players beyond the capacity of their court"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+	defer rows.Close()
+
+	var players []Player
+	for rows.Next() {
+
+		var player Player
+		var capacity int
+		err := rows.Scan(&player.Person, &player.Court, &player.Position, &capacity)
+		if err != nil {
+			message := "Could not scan the player"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return 0, err
+		}
+
+		f.DebugError(fmt.Sprintf("Inconsistant data: person [%d] is in position [%d] of court [%d], which has %d positions", player.Person, player.Position, player.Court, capacity))
+		players = append(players, player)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the players beyond the capacity of their court"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+	rows.Close()
+
+	if fix {
+		for _, player := range players {
+			f.DebugError(fmt.Sprintf("Moving person [%d] from court [%d] to the waiting list", player.Person, player.Court))
+
+			err = MakePlayerWaitTx(ctx, db, player.Person)
+			if err != nil {
+				message := fmt.Sprintf("Could not make player [%d] wait", player.Person)
+				f.Errorf(message)
+				f.DumpError(err, message)
+				return 0, err
+			}
+		}
+	}
+
+	return len(players), nil
+}
+
+func (person *FullPerson) CheckConistencyPerson(ctx context.Context, db Querier, fix bool) (int, error) {
 	f := functionCheckConistencyPerson
 
 	count := 0
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+func TestCanCheckIn(t *testing.T) {
+
+	for _, status := range AllStates {
+		p := FullPerson{Status: status}
+
+		allowed := (p.CanCheckIn() == nil)
+		expected := (status == StatusPlayer || status == StatusInactive)
+		if allowed != expected {
+			t.Logf("Unexpected CanCheckIn for status %s: %t, expected: %t", status, allowed, expected)
+			t.FailNow()
+		}
+
+		allowed = (p.CanCheckInOtherPeople() == nil)
+		expected = (status == StatusAdmin)
+		if allowed != expected {
+			t.Logf("Unexpected CanCheckInOtherPeople for status %s: %t, expected: %t", status, allowed, expected)
+			t.FailNow()
+		}
+	}
+}
+
+func TestCheckOutStatus(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	for _, status := range []string{StatusAdmin, StatusSuspended} {
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Log("Could not begin a new transaction")
+			t.FailNow()
+		}
+
+		r := Registration{
+			FirstName: "Check", LastName: "Out", Knownas: "checkout", Email: "checkout@mi6.gov.uk", Phone: "+44 1234 444444", Password: "TopSecret",
+		}
+
+		p, err := r.ToPerson()
+		if err != nil {
+			tx.Rollback()
+			t.Log(err.Error())
+			t.FailNow()
+		}
+		p.Status = status
+
+		err = p.SavePersonTx(ctx, tx)
+		if err != nil {
+			tx.Rollback()
+			t.Log("Could not create new person")
+			t.FailNow()
+		}
+
+		err = MakePersonInactiveTx(ctx, tx, p.ID)
+		tx.Rollback()
+
+		var codeError *codeerror.CodeError
+		if !errors.As(err, &codeError) || codeError.Status() != http.StatusBadRequest {
+			t.Logf("Unexpected result checking out a person with status %s: %v", status, err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestCheckInFromCourt(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	_, selections, err := FillCourt(db, courtID, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(selections) == 0 {
+		t.Log("Nobody was picked")
+		t.FailNow()
+	}
+	personID := selections[0].PersonId.ID
+
+	err = MakePersonPlayer(db, personID)
+	if err != nil {
+		t.Log("Could not check in the player")
+		t.FailNow()
+	}
+
+	players, err := ListPlayersForCourt(ctx, db, courtID)
+	if err != nil {
+		t.Log("Could not list the players")
+		t.FailNow()
+	}
+	for _, player := range players {
+		if player.Person == personID {
+			t.Logf("Person [%d] is still on court [%d]", personID, courtID)
+			t.FailNow()
+		}
+	}
+
+	waiters, err := ListWaiters(db)
+	if err != nil {
+		t.Log("Could not list the waiters")
+		t.FailNow()
+	}
+	if len(waiters) == 0 || waiters[len(waiters)-1].Person != personID {
+		t.Logf("Person [%d] is not at the back of the queue", personID)
+		t.FailNow()
+	}
+
+	games, err := ListGames(db, GameFilter{Court: courtID})
+	if err != nil {
+		t.Log("Could not list the games")
+		t.FailNow()
+	}
+	if len(games) != 1 || len(games[0].Positions) != len(selections) {
+		t.Logf("The game on court [%d] was not recorded when person [%d] left it", courtID, personID)
+		t.FailNow()
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/rsmaxwell/players-tt-api/internal/cmdline"
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
 	"github.com/rsmaxwell/players-tt-api/internal/config"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 )
@@ -19,24 +20,22 @@ var (
 	functionDeleteAllRecords   = debug.NewFunction(pkg, "deleteAllRecords")
 	functionFillCourtTx        = debug.NewFunction(pkg, "FillCourtTx")
 	functionFillCourt          = debug.NewFunction(pkg, "fillCourt")
+	functionFillAllCourtsTx    = debug.NewFunction(pkg, "FillAllCourtsTx")
+	functionFillAllCourts      = debug.NewFunction(pkg, "FillAllCourts")
 	functionClearCourtTx       = debug.NewFunction(pkg, "ClearCourtTx")
 	functionClearCourt         = debug.NewFunction(pkg, "clearCourt")
 	functionEndTransaction     = debug.NewFunction(pkg, "EndTransaction")
+	functionRunInTransaction   = debug.NewFunction(pkg, "RunInTransaction")
 )
 
-var (
-	// MetricsData containing metrics
-	MetricsData Metrics
-)
-
-// Metrics structure
-type Metrics struct {
-	StatusCodes map[int]int `json:"statusCodes"`
-}
-
-func init() {
-	MetricsData = Metrics{}
-	MetricsData.StatusCodes = make(map[int]int)
+// Querier is satisfied by both *sql.DB and *sql.Tx, so the *Tx functions run their
+// statements inside whichever transaction the caller has begun. A transaction runs on a single
+// connection, which cannot take another statement while rows are open, so a function which queries
+// again after reading its rows closes them first rather than waiting for the deferred close
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // Setup function
@@ -84,7 +83,7 @@ func Setup(t *testing.T) (func(t *testing.T), *sql.DB, *config.Config) {
 }
 
 // DeleteAllRecords
-func DeleteAllRecords(db *sql.DB) error {
+func DeleteAllRecords(db *sql.DB) (err error) {
 	f := functionDeleteAllRecords
 	ctx := context.Background()
 
@@ -94,9 +93,9 @@ func DeleteAllRecords(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = deleteAllRecordsTx(ctx, db)
+	err = deleteAllRecordsTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -105,11 +104,74 @@ func DeleteAllRecords(db *sql.DB) error {
 }
 
 // DeleteAllRecords removes all the records in the database
-func deleteAllRecordsTx(ctx context.Context, db *sql.DB) error {
+func deleteAllRecordsTx(ctx context.Context, db Querier) error {
 	f := functionDeleteAllRecordsTx
 
-	sqlStatement := "DELETE FROM " + PlayingTable
-	_, err := db.Exec(sqlStatement)
+	sqlStatement := "DELETE FROM " + ScoreTable
+	_, err := db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from scores"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + GamePlayerTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from game players"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + GameTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from games"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + AttendanceTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from attendance"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + SessionTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from sessions"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + NotificationTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from notifications"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + CallTable
+	_, err = db.ExecContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not delete all from calls"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "DELETE FROM " + PlayingTable
+	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not delete all from playing"
 		f.Errorf(message)
@@ -118,7 +180,7 @@ func deleteAllRecordsTx(ctx context.Context, db *sql.DB) error {
 	}
 
 	sqlStatement = "DELETE FROM " + WaitingTable
-	_, err = db.Exec(sqlStatement)
+	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not delete all from waiting"
 		f.Errorf(message)
@@ -127,7 +189,7 @@ func deleteAllRecordsTx(ctx context.Context, db *sql.DB) error {
 	}
 
 	sqlStatement = "DELETE FROM " + CourtTable
-	_, err = db.Exec(sqlStatement)
+	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not delete all from courts"
 		f.Errorf(message)
@@ -136,7 +198,7 @@ func deleteAllRecordsTx(ctx context.Context, db *sql.DB) error {
 	}
 
 	sqlStatement = "DELETE FROM " + PersonTable + " WHERE status != '" + StatusAdmin + "'"
-	_, err = db.Exec(sqlStatement)
+	_, err = db.ExecContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not delete all from people"
 		f.Errorf(message)
@@ -144,11 +206,28 @@ func deleteAllRecordsTx(ctx context.Context, db *sql.DB) error {
 		return err
 	}
 
+	// the history has gone, so the people who remain start again
+	sqlStatement = "UPDATE " + PersonTable + " SET rating = $1"
+	_, err = db.ExecContext(ctx, sqlStatement, InitialRating)
+	if err != nil {
+		message := "Could not reset the ratings"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
 	return nil
 }
 
+// CourtFill holds the positions of a court after it was filled, and who was picked for the empty positions
+type CourtFill struct {
+	Court      int         `json:"court"`
+	Positions  []Position  `json:"positions"`
+	Selections []Selection `json:"selections"`
+}
+
 // FillCourt
-func FillCourt(db *sql.DB, courtID int) ([]Position, error) {
+func FillCourt(db *sql.DB, courtID int, strategy FillStrategy) (positions []Position, selections []Selection, err error) {
 	f := functionFillCourt
 	ctx := context.Background()
 
@@ -156,99 +235,215 @@ func FillCourt(db *sql.DB, courtID int) ([]Position, error) {
 	if err != nil {
 		message := "Could not begin a new transaction"
 		f.DumpError(err, message)
-		return nil, err
+		return nil, nil, err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	positions, err := fillCourtTx(ctx, db, courtID)
+	positions, selections, err = FillCourtTx(ctx, tx, courtID, strategy)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return positions, nil
+	return positions, selections, nil
 }
 
-// FillCourt
-func fillCourtTx(ctx context.Context, db *sql.DB, courtID int) ([]Position, error) {
+// FillCourtTx puts waiters into the empty positions of a court, chosen by the strategy, and returns the
+// positions of the court and who was picked for each empty position. Each person picked is called to
+// the court
+func FillCourtTx(ctx context.Context, db Querier, courtID int, strategy FillStrategy) ([]Position, []Selection, error) {
 	f := functionFillCourtTx
 
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := Court{ID: courtID}
+	err = c.LoadCourtTx(ctx, db)
+	if err != nil {
+		message := fmt.Sprintf("Could not load the court [%d]", courtID)
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, nil, err
+	}
+
 	players, err := ListPlayersForCourt(ctx, db, courtID)
 	if err != nil {
 		message := "Could not list players"
 		f.Errorf(message)
 		f.DumpError(err, message)
-		return nil, err
+		return nil, nil, err
 	}
 
-	mapOfPlayers := make(map[int]*Player)
+	court := make(map[int]Candidate)
 	for _, player := range players {
-		p := player                        // take a copy of each object ...
-		mapOfPlayers[player.Position] = &p // ... so their references are actually different!
+		person := FullPerson{ID: player.Person}
+		err = person.LoadPersonTx(ctx, db)
+		if err != nil {
+			message := "Could not load player"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, nil, err
+		}
+
+		personId := PersonId{ID: player.Person, Knownas: person.Knownas}
+		court[player.Position] = Candidate{PersonId: personId, Rating: person.Rating}
+	}
+
+	candidates, err := ListCandidatesTx(ctx, db)
+	if err != nil {
+		message := "Could not list the candidates"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, nil, err
 	}
 
-	changes := 0
 	positions := make([]Position, 0)
-	for index := 0; index < NumberOfCourtPositions; index++ {
+	selections := make([]Selection, 0)
+	for index := 0; index < c.Capacity; index++ {
 
-		var ok bool
-		var player *Player
-		var personID int
+		if _, ok := court[index]; !ok {
 
-		if player, ok = mapOfPlayers[index]; !ok {
-			changes++
+			if len(candidates) == 0 {
+				message := "no more waiters"
+				f.Infof(message)
+				break
+			}
 
-			personID, err = GetFirstWaiter(ctx, db)
-			if err != nil {
-				message := "Could not get the first waiter"
-				f.Errorf(message)
+			i, reason := strategy.Pick(index, c.Capacity, court, candidates)
+			if i < 0 || i >= len(candidates) {
+				message := fmt.Sprintf("the strategy picked candidate %d of %d", i, len(candidates))
+				err = codeerror.NewInternalServerError(message)
 				f.DumpError(err, message)
-				return nil, err
+				return nil, nil, err
 			}
 
-			if personID <= 0 {
-				message := "no more waiters"
-				f.Infof(message)
-				break
+			candidate := candidates[i]
+			candidates = append(candidates[:i:i], candidates[i+1:]...)
+
+			waiters, err := ListWaitersForPerson(ctx, db, candidate.PersonId.ID)
+			if err != nil || len(waiters) == 0 {
+				message := fmt.Sprintf("Could not find the waiter [%d]", candidate.PersonId.ID)
+				f.Errorf(message)
+				if err == nil {
+					err = codeerror.NewInternalServerError(message)
+				}
+				f.DumpError(err, message)
+				return nil, nil, err
 			}
 
-			err = RemoveWaiter(ctx, db, personID)
+			err = RemoveWaiter(ctx, db, candidate.PersonId.ID)
 			if err != nil {
 				message := "Could not remove the waiter"
 				f.Errorf(message)
 				f.DumpError(err, message)
-				return nil, err
+				return nil, nil, err
 			}
 
-			err = AddPlayer(ctx, db, personID, courtID, index)
+			err = AddPlayer(ctx, db, candidate.PersonId.ID, courtID, index)
 			if err != nil {
 				message := "Could not add player"
 				f.Errorf(message)
 				f.DumpError(err, message)
-				return nil, err
+				return nil, nil, err
 			}
-			p := Player{Person: personID, Court: courtID, Position: index}
-			player = &p
+
+			err = callPlayerTx(ctx, db, waiters[0], &c, index)
+			if err != nil {
+				message := "Could not call the player"
+				f.Errorf(message)
+				f.DumpError(err, message)
+				return nil, nil, err
+			}
+
+			court[index] = candidate
+			selections = append(selections, Selection{Index: index, PersonId: candidate.PersonId, Reason: reason})
 		}
 
-		person := FullPerson{ID: player.Person}
-		err = person.LoadPersonTx(ctx, db)
+		position := Position{Index: index, PersonId: court[index].PersonId}
+		positions = append(positions, position)
+	}
+
+	return positions, selections, nil
+}
+
+// FillAllCourts
+func FillAllCourts(db *sql.DB, strategy FillStrategy) (list []CourtFill, err error) {
+	f := functionFillAllCourts
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = FillAllCourtsTx(ctx, tx, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// FillAllCourtsTx fills the empty positions of every court from the one waiting list, a court at a time
+// in the order the courts are listed, so that as many courts as possible have a full line-up. It
+// returns the positions of each court which had an empty position, and who was picked for them
+func FillAllCourtsTx(ctx context.Context, db Querier, strategy FillStrategy) ([]CourtFill, error) {
+	f := functionFillAllCourtsTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	courts, err := ListCourtsTx(ctx, db)
+	if err != nil {
+		message := "Could not list the courts"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	candidates, err := ListCandidatesTx(ctx, db)
+	if err != nil {
+		message := "Could not list the candidates"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+	remaining := len(candidates)
+
+	list := []CourtFill{}
+	for _, court := range courts {
+
+		if remaining == 0 {
+			break
+		}
+
+		if len(court.Positions) >= court.Capacity {
+			continue
+		}
+
+		positions, selections, err := FillCourtTx(ctx, db, court.ID, strategy)
 		if err != nil {
-			message := "Could not load player"
+			message := fmt.Sprintf("Could not fill court [%d]", court.ID)
 			f.Errorf(message)
 			f.DumpError(err, message)
 			return nil, err
 		}
+		remaining -= len(selections)
 
-		personId := PersonId{ID: player.Person, Knownas: person.Knownas}
-		position := Position{Index: player.Position, PersonId: personId}
-		positions = append(positions, position)
+		list = append(list, CourtFill{Court: court.ID, Positions: positions, Selections: selections})
 	}
 
-	return positions, nil
+	return list, nil
 }
 
 // ClearCourt
-func ClearCourt(db *sql.DB, courtID int) error {
+func ClearCourt(db *sql.DB, courtID int) (err error) {
 	f := functionClearCourt
 	ctx := context.Background()
 
@@ -259,9 +454,9 @@ func ClearCourt(db *sql.DB, courtID int) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = clearCourtTx(ctx, db, courtID)
+	err = ClearCourtTx(ctx, tx, courtID)
 	if err != nil {
 		message := "Problem clearing court"
 		f.Errorf(message)
@@ -273,9 +468,22 @@ func ClearCourt(db *sql.DB, courtID int) error {
 }
 
 // ClearCourt
-func clearCourtTx(ctx context.Context, db *sql.DB, courtID int) error {
+func ClearCourtTx(ctx context.Context, db Querier, courtID int) error {
 	f := functionClearCourtTx
 
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	_, err = EndGameTx(ctx, db, courtID)
+	if err != nil {
+		message := "Could not record the game"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
 	players, err := ListPlayersForCourt(ctx, db, courtID)
 	if err != nil {
 		message := "Could not list players"
@@ -316,7 +524,28 @@ func clearCourtTx(ctx context.Context, db *sql.DB, courtID int) error {
 	return nil
 }
 
-func EndTransaction(ctx context.Context, tx *sql.Tx, db *sql.DB, err error) error {
+// RunInTransaction runs several operations in one transaction, which is committed only if all of
+// them succeed
+func RunInTransaction(db *sql.DB, fn func(ctx context.Context, db Querier) error) (err error) {
+	f := functionRunInTransaction
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = fn(ctx, tx)
+	return err
+}
+
+// EndTransaction commits the transaction, or rolls it back on a panic, an error or inconsistent data.
+// It is deferred by the caller with a pointer to the caller's named error, so the final value of the
+// error is seen and any commit failure is passed back
+func EndTransaction(ctx context.Context, tx *sql.Tx, err *error) {
 	f := functionEndTransaction
 	f.DebugVerbose("")
 
@@ -325,28 +554,33 @@ func EndTransaction(ctx context.Context, tx *sql.Tx, db *sql.DB, err error) erro
 		f.DebugVerbose("Rollback on panic")
 		tx.Rollback()
 		panic(p)
-	} else if err != nil {
+	} else if *err != nil {
 		f.DebugVerbose("Rollback on error")
 		tx.Rollback()
+		if IsDatabaseError(*err) {
+			MetricsData.RecordDatabaseError()
+		}
 	} else {
 
-		count, err := CheckConistency(ctx, db, false)
-		if err != nil {
+		count, err2 := CheckConistency(ctx, tx, false)
+		if err2 != nil {
 			f.Errorf("Rollback on failed consistency check")
 			tx.Rollback()
-			return err
+			*err = err2
+			return
 		}
 		if count > 0 {
 			message := fmt.Sprintf("Rollback on inconsistant data: count: %d", count)
 			f.Errorf(message)
-			err = fmt.Errorf(message)
 			tx.Rollback()
-			return err
+			*err = fmt.Errorf(message)
+			return
 		}
 
 		f.DebugVerbose("Commit on success")
-		return tx.Commit()
+		*err = tx.Commit()
+		if *err != nil {
+			MetricsData.RecordDatabaseError()
+		}
 	}
-
-	return err
 }
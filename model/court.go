@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
 	"github.com/rsmaxwell/players-tt-api/internal/utils"
@@ -21,28 +22,45 @@ type Position struct {
 	PersonId PersonId `json:"personId"`
 }
 
-// Court type
+// Court type. A game on the court is limited to GameMinutes, or to the limit of the session when
+// that is zero. The deadline and the seconds remaining count down the game in progress, if it has a limit
 type Court struct {
-	ID        int        `json:"id" db:"id"`
-	Name      string     `json:"name" db:"name" validate:"required,min=3,max=20"`
-	Positions []Position `json:"positions" db:"positions"`
+	ID          int        `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name" validate:"required,min=3,max=20"`
+	Capacity    int        `json:"capacity" db:"capacity"`
+	GameMinutes int        `json:"gameMinutes" db:"game_minutes"`
+	Positions   []Position `json:"positions" db:"positions"`
+	Deadline    *time.Time `json:"deadline"`
+	Remaining   int        `json:"remaining"`
 }
 
 // Court type
 type PlainCourt struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Capacity    int    `json:"capacity"`
+	GameMinutes int    `json:"gameMinutes"`
 }
 
 // NullCourt type
 type NullCourt struct {
-	ID   int
-	Name sql.NullString
+	ID          int
+	Name        sql.NullString
+	Capacity    sql.NullInt32
+	GameMinutes sql.NullInt32
 }
 
 const (
-	CourtTable             = "court"
-	NumberOfCourtPositions = 4
+	CourtTable = "court"
+
+	// SinglesCapacity is the number of positions on a court used for singles
+	SinglesCapacity = 2
+
+	// DoublesCapacity is the number of positions on a court used for doubles
+	DoublesCapacity = 4
+
+	// DefaultCourtCapacity is the capacity of a court when none is given
+	DefaultCourtCapacity = DoublesCapacity
 )
 
 var (
@@ -62,6 +80,7 @@ var (
 func NewCourt(name string) *Court {
 	c := new(Court)
 	c.Name = name
+	c.Capacity = DefaultCourtCapacity
 	return c
 }
 
@@ -75,21 +94,58 @@ func NewCourtFromMap(data *map[string]interface{}) (*Court, error) {
 	}
 
 	court := NewCourt(name)
+
+	if _, ok := (*data)["capacity"]; ok {
+		court.Capacity, err = utils.GetIntegerFromMap("capacity", data)
+		if err != nil {
+			return nil, codeerror.NewBadRequest(err.Error())
+		}
+
+		err = ValidateCapacity(court.Capacity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := (*data)["gameMinutes"]; ok {
+		court.GameMinutes, err = utils.GetIntegerFromMap("gameMinutes", data)
+		if err != nil {
+			return nil, codeerror.NewBadRequest(err.Error())
+		}
+
+		err = ValidateGameMinutes(court.GameMinutes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return court, nil
 }
 
+// ValidateCapacity checks the capacity is for singles or doubles
+func ValidateCapacity(capacity int) error {
+
+	if capacity != SinglesCapacity && capacity != DoublesCapacity {
+		return codeerror.NewBadRequest(fmt.Sprintf("a court has %d positions for singles or %d for doubles, not %d", SinglesCapacity, DoublesCapacity, capacity))
+	}
+
+	return nil
+}
+
 func (c *Court) ToPlainCourt() *PlainCourt {
 
 	plainCourt := PlainCourt{
-		ID:   c.ID,
-		Name: c.Name,
+		ID:          c.ID,
+		Name:        c.Name,
+		Capacity:    c.Capacity,
+		GameMinutes: c.GameMinutes,
 	}
 
 	return &plainCourt
 }
 
 // SaveCourt method
-func (c *Court) SaveCourt(db *sql.DB) error {
+func (c *Court) SaveCourt(db *sql.DB) (err error) {
 	f := functionSaveCourt
 	ctx := context.Background()
 
@@ -100,9 +156,9 @@ func (c *Court) SaveCourt(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = c.SaveCourtTx(ctx, db)
+	err = c.SaveCourtTx(ctx, tx)
 	if err != nil {
 		message := "Could not SaveCourt"
 		f.DumpError(err, message)
@@ -113,11 +169,15 @@ func (c *Court) SaveCourt(db *sql.DB) error {
 }
 
 // SaveCourtTx writes a new Court to disk and returns the generated id
-func (c *Court) SaveCourtTx(ctx context.Context, db *sql.DB) error {
+func (c *Court) SaveCourtTx(ctx context.Context, db Querier) error {
 	f := functionSaveCourtTx
 
-	fields := "name"
-	values := basic.Quote(c.Name)
+	if c.Capacity == 0 {
+		c.Capacity = DefaultCourtCapacity
+	}
+
+	fields := "name, capacity, game_minutes"
+	values := basic.Quote(c.Name) + ", " + strconv.Itoa(c.Capacity) + ", " + strconv.Itoa(c.GameMinutes)
 
 	sqlStatement := "INSERT INTO " + CourtTable + " (" + fields + ") VALUES (" + values + ") RETURNING id"
 	err := db.QueryRowContext(ctx, sqlStatement).Scan(&c.ID)
@@ -132,10 +192,14 @@ func (c *Court) SaveCourtTx(ctx context.Context, db *sql.DB) error {
 }
 
 // UpdateCourt method
-func (c *Court) UpdateCourt(ctx context.Context, db *sql.DB) error {
+func (c *Court) UpdateCourt(ctx context.Context, db Querier) error {
 	f := functionUpdateCourt
 
-	items := "name=" + basic.Quote(c.Name)
+	if c.Capacity == 0 {
+		c.Capacity = DefaultCourtCapacity
+	}
+
+	items := "name=" + basic.Quote(c.Name) + ", capacity=" + strconv.Itoa(c.Capacity) + ", game_minutes=" + strconv.Itoa(c.GameMinutes)
 	sqlStatement := "UPDATE " + CourtTable + " SET " + items + " WHERE id=" + strconv.Itoa(c.ID)
 
 	_, err := db.ExecContext(ctx, sqlStatement)
@@ -149,7 +213,7 @@ func (c *Court) UpdateCourt(ctx context.Context, db *sql.DB) error {
 }
 
 // LoadCourt method
-func (c *Court) LoadCourt(db *sql.DB) error {
+func (c *Court) LoadCourt(db *sql.DB) (err error) {
 	f := functionLoadCourt
 	ctx := context.Background()
 
@@ -160,11 +224,11 @@ func (c *Court) LoadCourt(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = c.LoadCourtTx(ctx, db)
+	err = c.LoadCourtTx(ctx, tx)
 	if err != nil {
-		message := "Could not begin a new transaction"
+		message := "Could not load the court"
 		f.DumpError(err, message)
 		return err
 	}
@@ -173,11 +237,11 @@ func (c *Court) LoadCourt(db *sql.DB) error {
 }
 
 // LoadCourtTx returns the Court with the given ID
-func (c *Court) LoadCourtTx(ctx context.Context, db *sql.DB) error {
+func (c *Court) LoadCourtTx(ctx context.Context, db Querier) error {
 	f := functionLoadCourtTx
 
 	// Query the court
-	sqlStatement := "SELECT * FROM " + CourtTable + " WHERE ID=" + strconv.Itoa(c.ID)
+	sqlStatement := "SELECT id, name, capacity, game_minutes FROM " + CourtTable + " WHERE ID=" + strconv.Itoa(c.ID)
 	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not select all people"
@@ -191,7 +255,7 @@ func (c *Court) LoadCourtTx(ctx context.Context, db *sql.DB) error {
 		count++
 
 		var nc NullCourt
-		err := rows.Scan(&nc.ID, &nc.Name)
+		err := rows.Scan(&nc.ID, &nc.Name, &nc.Capacity, &nc.GameMinutes)
 		if err != nil {
 			message := "Could not scan the court"
 			f.DumpError(err, message)
@@ -200,6 +264,14 @@ func (c *Court) LoadCourtTx(ctx context.Context, db *sql.DB) error {
 		if nc.Name.Valid {
 			c.Name = nc.Name.String
 		}
+
+		if nc.Capacity.Valid {
+			c.Capacity = int(nc.Capacity.Int32)
+		}
+
+		if nc.GameMinutes.Valid {
+			c.GameMinutes = int(nc.GameMinutes.Int32)
+		}
 	}
 	err = rows.Err()
 	if err != nil {
@@ -221,7 +293,7 @@ func (c *Court) LoadCourtTx(ctx context.Context, db *sql.DB) error {
 }
 
 // DeleteCourt removes a court and associated playings
-func (c *Court) DeleteCourtTx(db *sql.DB) error {
+func (c *Court) DeleteCourtTx(db *sql.DB) (err error) {
 	f := functionDeleteCourtTx
 	ctx := context.Background()
 
@@ -231,25 +303,27 @@ func (c *Court) DeleteCourtTx(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
+	defer EndTransaction(ctx, tx, &err)
 
-	err = DeleteCourt(ctx, db, c.ID)
+	err = DeleteCourt(ctx, tx, c.ID)
 	if err != nil {
 		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		message := "Could not commit the transaction"
-		f.DumpError(err, message)
-		return err
-	}
-
 	return nil
 }
 
-func DeleteCourt(ctx context.Context, db *sql.DB, courtID int) error {
+func DeleteCourt(ctx context.Context, db Querier, courtID int) error {
 	f := functionDeleteCourt
 
+	_, err := EndGameTx(ctx, db, courtID)
+	if err != nil {
+		message := fmt.Sprintf("Could not record the game on court [%d]", courtID)
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
 	players, err := ListPlayersForCourt(ctx, db, courtID)
 	if err != nil {
 		message := "Could not delete playings"
@@ -288,7 +362,7 @@ func DeleteCourt(ctx context.Context, db *sql.DB, courtID int) error {
 }
 
 // ListCourts returns a list of the court IDs
-func ListCourts(db *sql.DB) ([]Court, error) {
+func ListCourts(db *sql.DB) (list []Court, err error) {
 	f := functionListCourts
 	ctx := context.Background()
 
@@ -299,11 +373,11 @@ func ListCourts(db *sql.DB) ([]Court, error) {
 		f.DumpError(err, message)
 		return nil, err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	list, err := ListCourtsTx(ctx, db)
+	list, err = ListCourtsTx(ctx, tx)
 	if err != nil {
-		message := "Could not begin a new transaction"
+		message := "Could not list the courts"
 		f.DumpError(err, message)
 		return nil, err
 	}
@@ -312,11 +386,11 @@ func ListCourts(db *sql.DB) ([]Court, error) {
 }
 
 // ListCourtsTx returns a list of the court IDs
-func ListCourtsTx(ctx context.Context, db *sql.DB) ([]Court, error) {
+func ListCourtsTx(ctx context.Context, db Querier) ([]Court, error) {
 	f := functionListCourtsTx
 
 	// Query the courts
-	returnedFields := []string{`id`, `name`}
+	returnedFields := []string{`id`, `name`, `capacity`, `game_minutes`}
 	sqlStatement := `SELECT ` + strings.Join(returnedFields, `, `) + ` FROM ` + CourtTable + ` ORDER BY ` + `name`
 	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
@@ -332,13 +406,34 @@ func ListCourtsTx(ctx context.Context, db *sql.DB) ([]Court, error) {
 		court := Court{}
 		court.Positions = make([]Position, 0)
 
-		err := rows.Scan(&court.ID, &court.Name)
+		err := rows.Scan(&court.ID, &court.Name, &court.Capacity, &court.GameMinutes)
 		if err != nil {
 			message := "Could not scan the court"
 			f.DumpError(err, message)
 			return nil, err
 		}
 
+		list = append(list, court)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list all from " + CourtTable
+		f.DumpError(err, message)
+		return nil, err
+	}
+	rows.Close()
+
+	deadlines, err := ListGameDeadlinesTx(ctx, db)
+	if err != nil {
+		message := "Could not list the deadlines of the games"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	for i := range list {
+		court := &list[i]
+
 		players, err := ListPlayersForCourt(ctx, db, court.ID)
 		if err != nil {
 			message := "Could not list the players on this court"
@@ -367,13 +462,10 @@ func ListCourtsTx(ctx context.Context, db *sql.DB) ([]Court, error) {
 			court.Positions = append(court.Positions, position)
 		}
 
-		list = append(list, court)
-	}
-	err = rows.Err()
-	if err != nil {
-		message := "Could not list all from " + CourtTable
-		f.DumpError(err, message)
-		return nil, err
+		if deadline, ok := deadlines[court.ID]; ok {
+			court.Deadline = &deadline
+			court.Remaining = Remaining(deadline, time.Now())
+		}
 	}
 
 	return list, nil
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Preview type is what a change would do to a court and the waiting list. Joined and Left are the
+// people the change would add to, or take from, the waiting list
+type Preview struct {
+	Positions []Position `json:"positions"`
+	Waiting   []PersonId `json:"waiting"`
+	Joined    []PersonId `json:"joined"`
+	Left      []PersonId `json:"left"`
+}
+
+var (
+	functionDryRun          = debug.NewFunction(pkg, "DryRun")
+	functionListQueueTx     = debug.NewFunction(pkg, "listQueueTx")
+	functionListPositionsTx = debug.NewFunction(pkg, "listPositionsTx")
+)
+
+// DryRun runs a change to a court in a transaction which is always rolled back, and returns a preview
+// of the court and the waiting list as the change would leave them. The change is checked for
+// consistency just as it would be if it were committed
+func DryRun(db *sql.DB, courtID int, fn func(ctx context.Context, db Querier) error) (*Preview, error) {
+	f := functionDryRun
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := listQueueTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fn(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := CheckConistency(ctx, tx, false)
+	if err != nil {
+		f.Errorf("Error checking consistency")
+		return nil, err
+	}
+	if count > 0 {
+		message := fmt.Sprintf("Inconsistant data: count: %d", count)
+		f.Errorf(message)
+		return nil, fmt.Errorf(message)
+	}
+
+	preview := new(Preview)
+
+	preview.Waiting, err = listQueueTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Positions, err = listPositionsTx(ctx, tx, courtID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Joined = difference(preview.Waiting, before)
+	preview.Left = difference(before, preview.Waiting)
+
+	return preview, nil
+}
+
+// difference returns the people in the first list who are not in the second
+func difference(list []PersonId, other []PersonId) []PersonId {
+
+	found := map[int]bool{}
+	for _, personId := range other {
+		found[personId.ID] = true
+	}
+
+	result := []PersonId{}
+	for _, personId := range list {
+		if !found[personId.ID] {
+			result = append(result, personId)
+		}
+	}
+
+	return result
+}
+
+// listQueueTx returns the people on the waiting list in queue order
+func listQueueTx(ctx context.Context, db Querier) ([]PersonId, error) {
+	f := functionListQueueTx
+
+	waiters, err := ListWaitersTx(ctx, db)
+	if err != nil {
+		message := "Could not list the waiters"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	list := []PersonId{}
+	for _, waiter := range waiters {
+		person := FullPerson{ID: waiter.Person}
+		err = person.LoadPersonTx(ctx, db)
+		if err != nil {
+			message := fmt.Sprintf("Could not load the waiter [%d]", waiter.Person)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+		list = append(list, PersonId{ID: person.ID, Knownas: person.Knownas})
+	}
+
+	return list, nil
+}
+
+// listPositionsTx returns the positions of a court in order
+func listPositionsTx(ctx context.Context, db Querier, courtID int) ([]Position, error) {
+	f := functionListPositionsTx
+
+	players, err := ListPlayersForCourt(ctx, db, courtID)
+	if err != nil {
+		message := "Could not list players"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	positions := []Position{}
+	for _, player := range players {
+		person := FullPerson{ID: player.Person}
+		err = person.LoadPersonTx(ctx, db)
+		if err != nil {
+			message := fmt.Sprintf("Could not load the player [%d]", player.Person)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+		positions = append(positions, Position{Index: player.Position, PersonId: PersonId{ID: person.ID, Knownas: person.Knownas}})
+	}
+
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Index < positions[j].Index })
+
+	return positions, nil
+}
@@ -0,0 +1,291 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Game type is a line-up on a court, recorded when it ends
+type Game struct {
+	ID        int        `json:"id"`
+	Court     int        `json:"court"`
+	CourtName string     `json:"courtName"`
+	Session   int        `json:"session"`
+	Capacity  int        `json:"capacity"`
+	Start     time.Time  `json:"start"`
+	Finish    time.Time  `json:"finish"`
+	Positions []Position `json:"positions"`
+	Scores    []SetScore `json:"scores"`
+}
+
+// GameFilter selects a page of the game history. Zero values select everything
+type GameFilter struct {
+	From     time.Time
+	To       time.Time
+	Court    int
+	Person   int
+	Session  int
+	Page     int
+	PageSize int
+}
+
+const (
+	// GameTable is the name of the game table
+	GameTable = "game"
+
+	// GamePlayerTable is the name of the table of the people in each game
+	GamePlayerTable = "game_player"
+)
+
+var (
+	functionEndGameTx      = debug.NewFunction(pkg, "EndGameTx")
+	functionEndGameOfTx    = debug.NewFunction(pkg, "endGameOfTx")
+	functionListGames      = debug.NewFunction(pkg, "ListGames")
+	functionListGamesTx    = debug.NewFunction(pkg, "ListGamesTx")
+	functionLoadGamePeople = debug.NewFunction(pkg, "loadGamePeople")
+)
+
+// EndGameTx records the line-up on the court as a finished game, and restarts the clock for the
+// players who stay on the court. The game started when the last of its players joined the court.
+// Nothing is recorded for an empty court
+func EndGameTx(ctx context.Context, db Querier, courtID int) (int, error) {
+	f := functionEndGameTx
+
+	players, err := ListPlayersForCourt(ctx, db, courtID)
+	if err != nil {
+		message := "Could not list players"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+
+	if len(players) == 0 {
+		return 0, nil
+	}
+
+	sqlStatement := `
+		INSERT INTO ` + GameTable + ` (court, court_name, session, capacity, start, finish)
+		SELECT c.id, c.name, (SELECT id FROM ` + SessionTable + ` WHERE closed IS NULL), c.capacity, MAX(p.start), CURRENT_TIMESTAMP
+		FROM ` + CourtTable + ` c JOIN ` + PlayingTable + ` p ON p.court = c.id
+		WHERE c.id = $1
+		GROUP BY c.id, c.name, c.capacity
+		RETURNING id`
+
+	var gameID int
+	err = db.QueryRowContext(ctx, sqlStatement, courtID).Scan(&gameID)
+	if err != nil {
+		message := "Could not insert into " + GameTable
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+
+	sqlStatement = "INSERT INTO " + GamePlayerTable + " (game, person, position) VALUES ($1, $2, $3)"
+	for _, player := range players {
+		_, err = db.ExecContext(ctx, sqlStatement, gameID, player.Person, player.Position)
+		if err != nil {
+			message := "Could not insert into " + GamePlayerTable
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return 0, err
+		}
+	}
+
+	sqlStatement = "UPDATE " + PlayingTable + " SET start = CURRENT_TIMESTAMP WHERE court=$1"
+	_, err = db.ExecContext(ctx, sqlStatement, courtID)
+	if err != nil {
+		message := "Could not restart the players on the court"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+
+	return gameID, nil
+}
+
+// endGameOfTx ends the game on the court a person is playing on, before they leave it. Nothing is
+// recorded for a person who is not on a court
+func endGameOfTx(ctx context.Context, db Querier, personID int) error {
+	f := functionEndGameOfTx
+
+	var courtID int
+	sqlStatement := "SELECT court FROM " + PlayingTable + " WHERE person=$1"
+	err := db.QueryRowContext(ctx, sqlStatement, personID).Scan(&courtID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		message := "Could not find the court of the player"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	_, err = EndGameTx(ctx, db, courtID)
+	if err != nil {
+		message := fmt.Sprintf("Could not record the game on court [%d]", courtID)
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
+	return nil
+}
+
+// ListGames returns a page of the game history, most recent first
+func ListGames(db *sql.DB, filter GameFilter) (list []Game, err error) {
+	f := functionListGames
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ListGamesTx(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListGamesTx returns a page of the game history, most recent first
+func ListGamesTx(ctx context.Context, db Querier, filter GameFilter) ([]Game, error) {
+	f := functionListGamesTx
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !filter.From.IsZero() {
+		addCondition("finish >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("start < $%d", filter.To)
+	}
+	if filter.Court > 0 {
+		addCondition("court = $%d", filter.Court)
+	}
+	if filter.Session > 0 {
+		addCondition("session = $%d", filter.Session)
+	}
+	if filter.Person > 0 {
+		addCondition("id IN (SELECT game FROM "+GamePlayerTable+" WHERE person = $%d)", filter.Person)
+	}
+
+	sqlStatement := "SELECT id, court, court_name, COALESCE(session, 0), capacity, start, finish FROM " + GameTable
+	if len(conditions) > 0 {
+		sqlStatement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sqlStatement += " ORDER BY finish DESC, id DESC"
+
+	if filter.PageSize > 0 {
+		args = append(args, filter.PageSize, filter.Page*filter.PageSize)
+		sqlStatement += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, sqlStatement, args...)
+	if err != nil {
+		message := "Could not list the games"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Game{}
+	for rows.Next() {
+
+		var game Game
+		err := rows.Scan(&game.ID, &game.Court, &game.CourtName, &game.Session, &game.Capacity, &game.Start, &game.Finish)
+		if err != nil {
+			message := "Could not scan the game"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		list = append(list, game)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the games"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	rows.Close()
+
+	for i := range list {
+		game := &list[i]
+		game.Positions, err = loadGamePeople(ctx, db, game.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		game.Scores, err = ListScoresTx(ctx, db, game.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// loadGamePeople returns the positions of a game. A person who has since been deleted has no name
+func loadGamePeople(ctx context.Context, db Querier, gameID int) ([]Position, error) {
+	f := functionLoadGamePeople
+
+	sqlStatement := `
+		SELECT g.position, g.person, COALESCE(p.knownas, '')
+		FROM ` + GamePlayerTable + ` g LEFT JOIN ` + PersonTable + ` p ON p.id = g.person
+		WHERE g.game = $1
+		ORDER BY g.position`
+
+	rows, err := db.QueryContext(ctx, sqlStatement, gameID)
+	if err != nil {
+		message := "Could not list the people in the game"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []Position{}
+	for rows.Next() {
+
+		var position Position
+		err := rows.Scan(&position.Index, &position.PersonId.ID, &position.PersonId.Knownas)
+		if err != nil {
+			message := "Could not scan the game position"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		positions = append(positions, position)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the people in the game"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return positions, nil
+}
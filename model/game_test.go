@@ -0,0 +1,218 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+func TestGames(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	positions, _, err := FillCourt(db, courtID, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(positions) == 0 {
+		t.Log("Could not find any players")
+		t.FailNow()
+	}
+
+	err = ClearCourt(db, courtID)
+	if err != nil {
+		t.Log("Could not clear the court")
+		t.FailNow()
+	}
+
+	games, err := ListGames(db, GameFilter{Court: courtID, PageSize: 10})
+	if err != nil {
+		t.Log("Could not list the games")
+		t.FailNow()
+	}
+	if len(games) != 1 {
+		t.Logf("Unexpected number of games: %d", len(games))
+		t.FailNow()
+	}
+	if len(games[0].Positions) != len(positions) {
+		t.Logf("Unexpected number of positions: %d, expected: %d", len(games[0].Positions), len(positions))
+		t.FailNow()
+	}
+
+	games, err = ListGames(db, GameFilter{Person: positions[0].PersonId.ID})
+	if err != nil {
+		t.Log("Could not list the games for a person")
+		t.FailNow()
+	}
+	if len(games) != 1 {
+		t.Logf("Unexpected number of games for a person: %d", len(games))
+		t.FailNow()
+	}
+
+	err = ClearCourt(db, courtID)
+	if err != nil {
+		t.Log("Could not clear the empty court")
+		t.FailNow()
+	}
+
+	games, err = ListGames(db, GameFilter{Court: courtID})
+	if err != nil {
+		t.Log("Could not list the games")
+		t.FailNow()
+	}
+	if len(games) != 1 {
+		t.Log("An empty court should not record a game")
+		t.FailNow()
+	}
+}
+
+func TestFillAllCourts(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	waiters, err := ListWaiters(db)
+	if err != nil {
+		t.Log("Could not list the waiters")
+		t.FailNow()
+	}
+
+	list, err := FillAllCourts(db, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the courts")
+		t.FailNow()
+	}
+	if len(list) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+
+	// the courts are filled in turn, so the front of the queue is on the first court
+	if len(list[0].Selections) == 0 || list[0].Selections[0].PersonId.ID != waiters[0].Person {
+		t.Logf("Unexpected first selection on court [%d]", list[0].Court)
+		t.FailNow()
+	}
+
+	picked := 0
+	for _, fill := range list {
+		picked += len(fill.Selections)
+	}
+
+	remaining, err := ListWaiters(db)
+	if err != nil {
+		t.Log("Could not list the waiters")
+		t.FailNow()
+	}
+	if len(remaining) != len(waiters)-picked {
+		t.Logf("Unexpected number of waiters: %d, expected: %d", len(remaining), len(waiters)-picked)
+		t.FailNow()
+	}
+}
+
+func TestDryRun(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	waiters, err := ListWaiters(db)
+	if err != nil {
+		t.Log("Could not list the waiters")
+		t.FailNow()
+	}
+
+	preview, err := DryRun(db, courtID, func(ctx context.Context, tx Querier) error {
+		_, _, err := FillCourtTx(ctx, tx, courtID, FirstComeStrategy{})
+		return err
+	})
+	if err != nil {
+		t.Log("Could not preview filling the court")
+		t.FailNow()
+	}
+	if len(preview.Positions) == 0 || len(preview.Left) != len(preview.Positions) {
+		t.Logf("Unexpected preview: %d positions, %d left the queue", len(preview.Positions), len(preview.Left))
+		t.FailNow()
+	}
+
+	after, err := ListWaiters(db)
+	if err != nil {
+		t.Log("Could not list the waiters")
+		t.FailNow()
+	}
+	if len(after) != len(waiters) {
+		t.Logf("Unexpected number of waiters after a dry run: %d, expected: %d", len(after), len(waiters))
+		t.FailNow()
+	}
+}
+
+func TestDeleteCourtGame(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) == 0 {
+		t.Log("Could not find any courts")
+		t.FailNow()
+	}
+	courtID := listOfCourts[0].ID
+
+	positions, _, err := FillCourt(db, courtID, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(positions) == 0 {
+		t.Log("Could not find any players")
+		t.FailNow()
+	}
+
+	c := Court{ID: courtID}
+	err = c.DeleteCourtTx(db)
+	if err != nil {
+		t.Log("Could not delete the court")
+		t.FailNow()
+	}
+
+	games, err := ListGames(db, GameFilter{Court: courtID})
+	if err != nil {
+		t.Log("Could not list the games")
+		t.FailNow()
+	}
+	if len(games) != 1 {
+		t.Logf("Unexpected number of games: %d", len(games))
+		t.FailNow()
+	}
+	if len(games[0].Positions) != len(positions) {
+		t.Logf("Unexpected number of positions: %d, expected: %d", len(games[0].Positions), len(positions))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+var (
+	// MetricsData containing metrics
+	MetricsData Metrics
+
+	// LatencyBuckets are the upper bounds, in seconds, of the request latency histogram buckets
+	LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// Metrics structure
+type Metrics struct {
+	mutex          sync.Mutex
+	StatusCodes    map[int]int                `json:"statusCodes"`
+	Commands       map[string]*CommandMetrics `json:"commands"`
+	Publications   int                        `json:"publications"`
+	DatabaseErrors int                        `json:"databaseErrors"`
+}
+
+// CommandMetrics structure
+type CommandMetrics struct {
+	Count       int         `json:"count"`
+	StatusCodes map[int]int `json:"statusCodes"`
+	Latency     Histogram   `json:"latency"`
+}
+
+// Histogram structure. Counts[i] is the number of observations no greater than Buckets[i] and greater
+// than the bucket before, with one extra count at the end for the observations above every bucket
+type Histogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []int     `json:"counts"`
+	Sum     float64   `json:"sum"`
+	Count   int       `json:"count"`
+}
+
+func init() {
+	MetricsData = Metrics{}
+	MetricsData.StatusCodes = make(map[int]int)
+	MetricsData.Commands = make(map[string]*CommandMetrics)
+}
+
+// RecordCommand records a handled request, its reply status and how long it took
+func (m *Metrics) RecordCommand(command string, status int, duration time.Duration) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.StatusCodes[status]++
+
+	c, ok := m.Commands[command]
+	if !ok {
+		c = &CommandMetrics{StatusCodes: make(map[int]int)}
+		c.Latency.Buckets = LatencyBuckets
+		c.Latency.Counts = make([]int, len(LatencyBuckets)+1)
+		m.Commands[command] = c
+	}
+
+	c.Count++
+	c.StatusCodes[status]++
+	c.Latency.observe(duration.Seconds())
+}
+
+func (h *Histogram) observe(value float64) {
+
+	i := 0
+	for i < len(h.Buckets) && value > h.Buckets[i] {
+		i++
+	}
+
+	h.Counts[i]++
+	h.Sum += value
+	h.Count++
+}
+
+// RecordPublish records a message published by the publisher
+func (m *Metrics) RecordPublish() {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Publications++
+}
+
+// RecordDatabaseError records a failed database operation
+func (m *Metrics) RecordDatabaseError() {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.DatabaseErrors++
+}
+
+// Snapshot returns a copy of the metrics, which is safe to read while requests carry on
+func (m *Metrics) Snapshot() *Metrics {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	snapshot := &Metrics{
+		StatusCodes:    make(map[int]int),
+		Commands:       make(map[string]*CommandMetrics),
+		Publications:   m.Publications,
+		DatabaseErrors: m.DatabaseErrors,
+	}
+
+	for status, count := range m.StatusCodes {
+		snapshot.StatusCodes[status] = count
+	}
+
+	for command, c := range m.Commands {
+		cm := &CommandMetrics{Count: c.Count, StatusCodes: make(map[int]int), Latency: c.Latency}
+		for status, count := range c.StatusCodes {
+			cm.StatusCodes[status] = count
+		}
+		cm.Latency.Counts = append([]int{}, c.Latency.Counts...)
+		snapshot.Commands[command] = cm
+	}
+
+	return snapshot
+}
+
+// IsDatabaseError reports whether the error came from the database rather than from a check made by
+// the model
+func IsDatabaseError(err error) bool {
+
+	var pgError pgx.PgError
+	if errors.As(err, &pgError) {
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, sql.ErrTxDone)
+}
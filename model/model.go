@@ -72,10 +72,24 @@ var (
 )
 
 // Populate adds a new set of standard records
-func Populate(db *sql.DB) error {
+func Populate(db *sql.DB) (err error) {
 	f := functionPopulate
 	ctx := context.Background()
 
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	_, err = OpenSessionTx(ctx, tx, 0)
+	if err != nil {
+		f.Errorf("Could not open a session")
+		return err
+	}
+
 	peopleData := []Registration{
 		{FirstName: GoodFirstName, LastName: GoodLastName, Knownas: GoodDisplayName, Email: GoodEmail, Phone: GoodPhone, Password: GoodPassword},
 		{FirstName: AnotherFirstName, LastName: AnotherLastName, Knownas: AnotherKnownas, Email: AnotherEmail, Phone: AnotherPhone, Password: AnotherPassword},
@@ -102,13 +116,13 @@ func Populate(db *sql.DB) error {
 
 		p.Status = StatusPlayer
 
-		err = p.SavePerson(db)
+		err = p.SavePersonTx(ctx, tx)
 		if err != nil {
 			f.Errorf("Could not save person: firstName: %s, lastname: %s, email: %s", p.FirstName, p.LastName, p.Email)
 			return err
 		}
 
-		err = AddWaiter(ctx, db, p.ID)
+		err = AddWaiter(ctx, tx, p.ID)
 		if err != nil {
 			f.Errorf("Could not add waiting")
 			return err
@@ -127,7 +141,7 @@ func Populate(db *sql.DB) error {
 	courtIDs := make(map[int]int)
 	for i, x := range courtData {
 		c := Court{Name: x.name}
-		err := c.SaveCourtTx(ctx, db)
+		err = c.SaveCourtTx(ctx, tx)
 		if err != nil {
 			message := "Could not save court"
 			f.Errorf(message)
@@ -140,7 +154,7 @@ func Populate(db *sql.DB) error {
 }
 
 // MakePlayerWait moves a person from playing to waiting
-func MakePlayerWait(db *sql.DB, personID int) error {
+func MakePlayerWait(db *sql.DB, personID int) (err error) {
 	f := functionMakePlayerWait
 	ctx := context.Background()
 
@@ -151,9 +165,9 @@ func MakePlayerWait(db *sql.DB, personID int) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = MakePlayerWaitTx(ctx, db, personID)
+	err = MakePlayerWaitTx(ctx, tx, personID)
 	if err != nil {
 		return err
 	}
@@ -161,7 +175,7 @@ func MakePlayerWait(db *sql.DB, personID int) error {
 	return nil
 }
 
-func MakePlayerWaitTx(ctx context.Context, db *sql.DB, personID int) error {
+func MakePlayerWaitTx(ctx context.Context, db Querier, personID int) error {
 
 	person := FullPerson{ID: personID}
 	err := person.LoadPersonTx(ctx, db)
@@ -191,7 +205,7 @@ func MakePlayerWaitTx(ctx context.Context, db *sql.DB, personID int) error {
 }
 
 // MakePlayerPlaying moves a person from playing to waiting
-func MakePlayerPlay(db *sql.DB, personID int, courtID int, position int) error {
+func MakePlayerPlay(db *sql.DB, personID int, courtID int, position int) (err error) {
 	f := functionMakePlayerPlay
 	ctx := context.Background()
 
@@ -202,9 +216,9 @@ func MakePlayerPlay(db *sql.DB, personID int, courtID int, position int) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = MakePlayerPlayTx(ctx, db, personID, courtID, position)
+	err = MakePlayerPlayTx(ctx, tx, personID, courtID, position)
 	if err != nil {
 		return err
 	}
@@ -212,10 +226,15 @@ func MakePlayerPlay(db *sql.DB, personID int, courtID int, position int) error {
 	return nil
 }
 
-func MakePlayerPlayTx(ctx context.Context, db *sql.DB, personID int, courtID int, position int) error {
+func MakePlayerPlayTx(ctx context.Context, db Querier, personID int, courtID int, position int) error {
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
 
 	person := FullPerson{ID: personID}
-	err := person.LoadPersonTx(ctx, db)
+	err = person.LoadPersonTx(ctx, db)
 	if err != nil {
 		return codeerror.NewNotFound(fmt.Sprintf("person [%d] not found", personID))
 	}
@@ -231,7 +250,7 @@ func MakePlayerPlayTx(ctx context.Context, db *sql.DB, personID int, courtID int
 	if position < 0 {
 		return codeerror.NewBadRequest(fmt.Sprintf("Unexpected position: %d", position))
 	}
-	if position >= NumberOfCourtPositions {
+	if position >= court.Capacity {
 		return codeerror.NewBadRequest(fmt.Sprintf("Unexpected position: %d", position))
 	}
 
@@ -254,7 +273,7 @@ func MakePlayerPlayTx(ctx context.Context, db *sql.DB, personID int, courtID int
 }
 
 // MakePersonInactive sets the status of a person to 'inactive'
-func MakePersonInactive(db *sql.DB, personID int) error {
+func MakePersonInactive(db *sql.DB, personID int) (err error) {
 	f := functionMakePersonInactive
 	ctx := context.Background()
 
@@ -265,9 +284,9 @@ func MakePersonInactive(db *sql.DB, personID int) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = MakePersonInactiveTx(ctx, db, personID)
+	err = MakePersonInactiveTx(ctx, tx, personID)
 	if err != nil {
 		return err
 	}
@@ -275,7 +294,7 @@ func MakePersonInactive(db *sql.DB, personID int) error {
 	return nil
 }
 
-func MakePersonInactiveTx(ctx context.Context, db *sql.DB, personID int) error {
+func MakePersonInactiveTx(ctx context.Context, db Querier, personID int) error {
 
 	person := FullPerson{ID: personID}
 	err := person.LoadPersonTx(ctx, db)
@@ -283,6 +302,15 @@ func MakePersonInactiveTx(ctx context.Context, db *sql.DB, personID int) error {
 		return codeerror.NewNotFound(fmt.Sprintf("person [%d] not found", personID))
 	}
 
+	if person.Status != StatusPlayer && person.Status != StatusInactive {
+		return codeerror.NewBadRequest(fmt.Sprintf("Cannot change person [%d] from %s to %s state", personID, person.Status, StatusInactive))
+	}
+
+	err = endGameOfTx(ctx, db, personID)
+	if err != nil {
+		return err
+	}
+
 	err = RemovePlayer(ctx, db, personID)
 	if err != nil {
 		return err
@@ -304,7 +332,7 @@ func MakePersonInactiveTx(ctx context.Context, db *sql.DB, personID int) error {
 }
 
 // MakePersonPlayer sets the status of a person to 'player'
-func MakePersonPlayer(db *sql.DB, personID int) error {
+func MakePersonPlayer(db *sql.DB, personID int) (err error) {
 	f := functionMakePersonPlayer
 	ctx := context.Background()
 
@@ -315,9 +343,9 @@ func MakePersonPlayer(db *sql.DB, personID int) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = MakePersonPlayerTx(ctx, db, personID)
+	err = MakePersonPlayerTx(ctx, tx, personID)
 	if err != nil {
 		return err
 	}
@@ -325,9 +353,14 @@ func MakePersonPlayer(db *sql.DB, personID int) error {
 	return nil
 }
 
-func MakePersonPlayerTx(ctx context.Context, db *sql.DB, personID int) error {
+func MakePersonPlayerTx(ctx context.Context, db Querier, personID int) error {
 	f := functionMakePersonPlayerTx
 
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
 	players, err := ListPlayersForPerson(ctx, db, personID)
 	if err != nil {
 		return err
@@ -378,6 +411,11 @@ func MakePersonPlayerTx(ctx context.Context, db *sql.DB, personID int) error {
 	}
 
 	if len(players) > 0 {
+		err = endGameOfTx(ctx, db, personID)
+		if err != nil {
+			return err
+		}
+
 		err = RemovePlayer(ctx, db, personID)
 		if err != nil {
 			return err
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// CourtPlace is a position on a court, with the person the client expects to find there, if anyone
+type CourtPlace struct {
+	Court    int
+	Index    int
+	Original *PersonId
+}
+
+var (
+	functionMovePlayer    = debug.NewFunction(pkg, "MovePlayer")
+	functionMovePlayerTx  = debug.NewFunction(pkg, "MovePlayerTx")
+	functionSwapPlayers   = debug.NewFunction(pkg, "SwapPlayers")
+	functionSwapPlayersTx = debug.NewFunction(pkg, "SwapPlayersTx")
+	functionCheckPlaceTx  = debug.NewFunction(pkg, "checkPlaceTx")
+)
+
+// MovePlayer moves a player to an empty position, on the same court or another one
+func MovePlayer(db *sql.DB, from CourtPlace, to CourtPlace) (err error) {
+	f := functionMovePlayer
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = MovePlayerTx(ctx, tx, from, to)
+	return err
+}
+
+// MovePlayerTx moves a player to an empty position, on the same court or another one. The player
+// keeps their place on a court rather than going back to the waiting list. The games of the line-ups
+// on the courts are ended
+func MovePlayerTx(ctx context.Context, db Querier, from CourtPlace, to CourtPlace) error {
+	f := functionMovePlayerTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if from.Court == to.Court && from.Index == to.Index {
+		return codeerror.NewBadRequest(fmt.Sprintf("position [%d] on court [%d] is both the start and the end of the move", from.Index, from.Court))
+	}
+
+	personID, err := checkPlaceTx(ctx, db, from)
+	if err != nil {
+		return err
+	}
+	if personID == nil {
+		return codeerror.NewBadRequest(fmt.Sprintf("there is no player at position [%d] on court [%d]", from.Index, from.Court))
+	}
+
+	otherID, err := checkPlaceTx(ctx, db, to)
+	if err != nil {
+		return err
+	}
+	if otherID != nil {
+		return codeerror.NewConflict(fmt.Sprintf("position [%d] on court [%d] is taken by [%d]", to.Index, to.Court, *otherID))
+	}
+
+	courts := []int{from.Court}
+	if to.Court != from.Court {
+		courts = append(courts, to.Court)
+	}
+
+	for _, courtID := range courts {
+		_, err = EndGameTx(ctx, db, courtID)
+		if err != nil {
+			message := fmt.Sprintf("Could not record the game on court [%d]", courtID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return err
+		}
+	}
+
+	return MakePlayerPlayTx(ctx, db, *personID, to.Court, to.Index)
+}
+
+// SwapPlayers exchanges the positions of two players
+func SwapPlayers(db *sql.DB, first CourtPlace, second CourtPlace) (err error) {
+	f := functionSwapPlayers
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = SwapPlayersTx(ctx, tx, first, second)
+	return err
+}
+
+// SwapPlayersTx exchanges the positions of two players, on the same court or on two courts. The
+// games of the line-ups on the courts are ended
+func SwapPlayersTx(ctx context.Context, db Querier, first CourtPlace, second CourtPlace) error {
+	f := functionSwapPlayersTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if first.Court == second.Court && first.Index == second.Index {
+		return codeerror.NewBadRequest(fmt.Sprintf("cannot swap position [%d] on court [%d] with itself", first.Index, first.Court))
+	}
+
+	firstID, err := checkPlaceTx(ctx, db, first)
+	if err != nil {
+		return err
+	}
+	if firstID == nil {
+		return codeerror.NewBadRequest(fmt.Sprintf("there is no player at position [%d] on court [%d]", first.Index, first.Court))
+	}
+
+	secondID, err := checkPlaceTx(ctx, db, second)
+	if err != nil {
+		return err
+	}
+	if secondID == nil {
+		return codeerror.NewBadRequest(fmt.Sprintf("there is no player at position [%d] on court [%d]", second.Index, second.Court))
+	}
+
+	courts := []int{first.Court}
+	if second.Court != first.Court {
+		courts = append(courts, second.Court)
+	}
+
+	for _, courtID := range courts {
+		_, err = EndGameTx(ctx, db, courtID)
+		if err != nil {
+			message := fmt.Sprintf("Could not record the game on court [%d]", courtID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return err
+		}
+	}
+
+	err = MakePlayerPlayTx(ctx, db, *firstID, second.Court, second.Index)
+	if err != nil {
+		return err
+	}
+
+	return MakePlayerPlayTx(ctx, db, *secondID, first.Court, first.Index)
+}
+
+// checkPlaceTx returns the person at a position on a court, or nil if it is empty. The person must be
+// the one the client expects, so that a change made since the client last looked is not overwritten
+func checkPlaceTx(ctx context.Context, db Querier, place CourtPlace) (*int, error) {
+	f := functionCheckPlaceTx
+
+	court := Court{ID: place.Court}
+	err := court.LoadCourtTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if place.Index < 0 || place.Index >= court.Capacity {
+		message := fmt.Sprintf("position [%d] is not on court [%d], which has %d positions", place.Index, place.Court, court.Capacity)
+		f.DebugVerbose(message)
+		return nil, codeerror.NewBadRequest(message)
+	}
+
+	players, err := GetPlayersForCourtAsMap(ctx, db, place.Court)
+	if err != nil {
+		message := "Could not list players"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	var personID *int
+	player, ok := players[place.Index]
+	if ok {
+		personID = &player.Person
+	}
+
+	err = checkOriginalPlayer(place.Index, personID, place.Original)
+	if err != nil {
+		return nil, codeerror.NewConflict(fmt.Sprintf("court [%d]: %s", place.Court, err.Error()))
+	}
+
+	return personID, nil
+}
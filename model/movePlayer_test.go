@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+func TestMoveAndSwapPlayers(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	listOfCourts, err := ListCourts(db)
+	if err != nil {
+		t.Log("Could not list the courts")
+		t.FailNow()
+	}
+	if len(listOfCourts) < 2 {
+		t.Logf("Unexpected number of courts: %d", len(listOfCourts))
+		t.FailNow()
+	}
+	courtA := listOfCourts[0].ID
+	courtB := listOfCourts[1].ID
+
+	positions, _, err := FillCourt(db, courtA, FirstComeStrategy{})
+	if err != nil {
+		t.Log("Could not fill the court")
+		t.FailNow()
+	}
+	if len(positions) < 2 {
+		t.Logf("Unexpected number of positions: %d", len(positions))
+		t.FailNow()
+	}
+	first := positions[0]
+	second := positions[1]
+
+	from := CourtPlace{Court: courtA, Index: first.Index, Original: &second.PersonId}
+	to := CourtPlace{Court: courtB, Index: 0}
+	err = MovePlayer(db, from, to)
+	if err == nil {
+		t.Log("Unexpected move of a player who was not at the position")
+		t.FailNow()
+	}
+
+	from.Original = &first.PersonId
+	err = MovePlayer(db, from, to)
+	if err != nil {
+		t.Log("Could not move the player")
+		t.FailNow()
+	}
+
+	players, err := GetPlayersForCourtAsMap(ctx, db, courtB)
+	if err != nil {
+		t.Log("Could not list the players")
+		t.FailNow()
+	}
+	if players[0].Person != first.PersonId.ID {
+		t.Logf("Unexpected player on court [%d]: %d, expected: %d", courtB, players[0].Person, first.PersonId.ID)
+		t.FailNow()
+	}
+
+	err = SwapPlayers(db, CourtPlace{Court: courtB, Index: 0, Original: &first.PersonId}, CourtPlace{Court: courtA, Index: second.Index, Original: &second.PersonId})
+	if err != nil {
+		t.Log("Could not swap the players")
+		t.FailNow()
+	}
+
+	players, err = GetPlayersForCourtAsMap(ctx, db, courtB)
+	if err != nil {
+		t.Log("Could not list the players")
+		t.FailNow()
+	}
+	if players[0].Person != second.PersonId.ID {
+		t.Logf("Unexpected player on court [%d]: %d, expected: %d", courtB, players[0].Person, second.PersonId.ID)
+		t.FailNow()
+	}
+}
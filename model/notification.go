@@ -0,0 +1,341 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Notification type is a message for one person. It is kept until the person acknowledges it, or for
+// at most NotificationRetention, so that a message sent while they were away can be delivered when they
+// come back
+type Notification struct {
+	ID      int       `json:"id"`
+	Person  int       `json:"person"`
+	Created time.Time `json:"created"`
+	Kind    string    `json:"kind"`
+	Text    string    `json:"text"`
+}
+
+const (
+	// NotificationTable is the name of the notification table
+	NotificationTable = "notification"
+
+	// NotificationCalled tells a person they have been called to a court
+	NotificationCalled = "called"
+
+	// NotificationQueuePosition tells a waiter where they now are in the queue
+	NotificationQueuePosition = "queuePosition"
+
+	// NotificationApproved tells a person their registration was approved
+	NotificationApproved = "registrationApproved"
+
+	// NotificationRetention is how long a notification which is never acknowledged is kept
+	NotificationRetention = 24 * time.Hour
+)
+
+var (
+	functionNotifyTx               = debug.NewFunction(pkg, "notifyTx")
+	functionNotifyQueuePositions   = debug.NewFunction(pkg, "NotifyQueuePositions")
+	functionNotifyQueuePositionsTx = debug.NewFunction(pkg, "NotifyQueuePositionsTx")
+	functionListNewNotifications   = debug.NewFunction(pkg, "ListNewNotifications")
+	functionListNewNotificationsTx = debug.NewFunction(pkg, "ListNewNotificationsTx")
+	functionListNotifications      = debug.NewFunction(pkg, "ListNotifications")
+	functionListNotificationsTx    = debug.NewFunction(pkg, "ListNotificationsTx")
+	functionScanNotifications      = debug.NewFunction(pkg, "scanNotifications")
+	functionNotifyApprovedTx       = debug.NewFunction(pkg, "notifyApprovedTx")
+)
+
+// Ordinal returns a number as '1st', '2nd', '3rd', '4th' and so on
+func Ordinal(n int) string {
+
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// QueuePositions returns the position of each waiter in a queue which is in order, counting from 1. A
+// paused waiter is not going to be picked, so has no position and is not counted
+func QueuePositions(waiters []Waiter) map[int]int {
+
+	positions := map[int]int{}
+	position := 0
+	for _, waiter := range waiters {
+		if waiter.Paused {
+			continue
+		}
+		position++
+		positions[waiter.Person] = position
+	}
+
+	return positions
+}
+
+// notifyTx stores a notification for a person, to be published with the next update of the publications
+func notifyTx(ctx context.Context, db Querier, personID int, kind string, text string) error {
+	f := functionNotifyTx
+
+	fields := "person, created, kind, text"
+	values := "$1, CURRENT_TIMESTAMP, $2, $3"
+	sqlStatement := "INSERT INTO " + NotificationTable + " (" + fields + ") VALUES (" + values + ")"
+
+	_, err := db.ExecContext(ctx, sqlStatement, personID, kind, text)
+	if err != nil {
+		message := "Could not insert into " + NotificationTable
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	return nil
+}
+
+// notifyApprovedTx tells a person their registration was approved, when their status changes from suspended
+func notifyApprovedTx(ctx context.Context, db Querier, personID int, before string, after string) error {
+	f := functionNotifyApprovedTx
+
+	if before != StatusSuspended || after == StatusSuspended {
+		return nil
+	}
+
+	err := notifyTx(ctx, db, personID, NotificationApproved, "Your registration was approved")
+	if err != nil {
+		message := fmt.Sprintf("Could not tell person [%d] their registration was approved", personID)
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
+	return nil
+}
+
+// NotifyQueuePositions tells each waiter whose position in the queue has changed where they now are
+func NotifyQueuePositions(db *sql.DB) (err error) {
+	f := functionNotifyQueuePositions
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = NotifyQueuePositionsTx(ctx, tx)
+	return err
+}
+
+// NotifyQueuePositionsTx tells each waiter whose position in the queue has changed where they now are.
+// The position each waiter was last told is kept in the waiting table
+func NotifyQueuePositionsTx(ctx context.Context, db Querier) error {
+	f := functionNotifyQueuePositionsTx
+
+	waiters, err := ListWaitersTx(ctx, db)
+	if err != nil {
+		message := "Could not list the waiters"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
+	sqlStatement := "SELECT person, notified FROM " + WaitingTable
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the positions the waiters were told"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+	defer rows.Close()
+
+	notified := map[int]int{}
+	for rows.Next() {
+		var personID, position int
+		err := rows.Scan(&personID, &position)
+		if err != nil {
+			message := "Could not scan the waiter"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return err
+		}
+		notified[personID] = position
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the positions the waiters were told"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+
+	rows.Close()
+
+	positions := QueuePositions(waiters)
+	for _, waiter := range waiters {
+		position := positions[waiter.Person]
+		if position == notified[waiter.Person] {
+			continue
+		}
+
+		if position > 0 {
+			text := fmt.Sprintf("You are now %s in the queue", Ordinal(position))
+			err = notifyTx(ctx, db, waiter.Person, NotificationQueuePosition, text)
+			if err != nil {
+				return err
+			}
+		}
+
+		sqlStatement = "UPDATE " + WaitingTable + " SET notified=$1 WHERE person=$2"
+		_, err = db.ExecContext(ctx, sqlStatement, position, waiter.Person)
+		if err != nil {
+			message := "Could not update the waiter"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListNewNotifications returns the notifications which have not been published yet
+func ListNewNotifications(db *sql.DB) (list []Notification, err error) {
+	f := functionListNewNotifications
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ListNewNotificationsTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListNewNotificationsTx returns the notifications which have not been published yet, in order, and
+// marks them as published. Notifications older than the retention are deleted
+func ListNewNotificationsTx(ctx context.Context, db Querier) ([]Notification, error) {
+	f := functionListNewNotificationsTx
+
+	sqlStatement := "DELETE FROM " + NotificationTable + " WHERE created < $1"
+	_, err := db.ExecContext(ctx, sqlStatement, time.Now().Add(-NotificationRetention))
+	if err != nil {
+		message := "Could not delete the old notifications"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	sqlStatement = `
+		UPDATE ` + NotificationTable + ` SET published = TRUE
+		WHERE NOT published
+		RETURNING id, person, created, kind, text`
+
+	list, err := scanNotifications(ctx, db, sqlStatement)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// ListNotifications returns a person's notifications after the one they last received
+func ListNotifications(db *sql.DB, personID int, after int) (list []Notification, err error) {
+	f := functionListNotifications
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ListNotificationsTx(ctx, tx, personID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListNotificationsTx returns a person's notifications with an id after the given one, in order. The
+// notifications up to it have been received, so they are deleted
+func ListNotificationsTx(ctx context.Context, db Querier, personID int, after int) ([]Notification, error) {
+	f := functionListNotificationsTx
+
+	sqlStatement := "DELETE FROM " + NotificationTable + " WHERE person=$1 AND id <= $2"
+	_, err := db.ExecContext(ctx, sqlStatement, personID, after)
+	if err != nil {
+		message := "Could not delete the notifications received"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	sqlStatement = "SELECT id, person, created, kind, text FROM " + NotificationTable + " WHERE person=$1 AND id > $2 ORDER BY id"
+
+	return scanNotifications(ctx, db, sqlStatement, personID, after)
+}
+
+func scanNotifications(ctx context.Context, db Querier, sqlStatement string, args ...interface{}) ([]Notification, error) {
+	f := functionScanNotifications
+
+	rows, err := db.QueryContext(ctx, sqlStatement, args...)
+	if err != nil {
+		message := "Could not list the notifications"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Notification{}
+	for rows.Next() {
+
+		var notification Notification
+		err := rows.Scan(&notification.ID, &notification.Person, &notification.Created, &notification.Kind, &notification.Text)
+		if err != nil {
+			message := "Could not scan the notification"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		list = append(list, notification)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the notifications"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestOrdinal(t *testing.T) {
+
+	cases := map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 102: "102nd", 111: "111th"}
+
+	for n, expected := range cases {
+		if Ordinal(n) != expected {
+			t.Logf("Unexpected ordinal of %d: expected %s, got %s", n, expected, Ordinal(n))
+			t.FailNow()
+		}
+	}
+}
+
+func TestQueuePositions(t *testing.T) {
+
+	waiters := []Waiter{
+		{Person: 4},
+		{Person: 2, Paused: true},
+		{Person: 7},
+		{Person: 1},
+	}
+
+	positions := QueuePositions(waiters)
+
+	expected := map[int]int{4: 1, 7: 2, 1: 3}
+	for person, position := range expected {
+		if positions[person] != position {
+			t.Logf("Unexpected position of person [%d]: expected %d, got %d", person, position, positions[person])
+			t.FailNow()
+		}
+	}
+
+	if _, ok := positions[2]; ok {
+		t.Log("The paused waiter has a position")
+		t.FailNow()
+	}
+}
@@ -31,6 +31,7 @@ type Person struct {
 	Email     string `json:"email"`
 	Phone     string `json:"phone"`
 	Status    string `json:"status"`
+	Rating    int    `json:"rating"`
 }
 
 // Person type
@@ -43,6 +44,7 @@ type FullPerson struct {
 	Phone     string `json:"phone" validate:"required,min=3,max=20"`
 	Hash      []byte `json:"hash"`
 	Status    string `json:"status"`
+	Rating    int    `json:"rating"`
 }
 
 // NullPerson type
@@ -55,6 +57,7 @@ type NullPerson struct {
 	Phone     sql.NullString `db:"phone"`
 	Hash      sql.NullString `db:"hash"`
 	Status    sql.NullString `db:"status"`
+	Rating    sql.NullInt32  `db:"rating"`
 }
 
 const (
@@ -112,6 +115,7 @@ func NewPerson(firstname string, lastname string, knownas string, email string,
 	p.Phone = phone
 	p.Hash = hash
 	p.Status = StatusSuspended
+	p.Rating = InitialRating
 	return p
 }
 
@@ -154,7 +158,7 @@ func NewPersonFromMap(data *map[string]interface{}) (*Registration, error) {
 }
 
 // DeletePerson removes a person and associated waiters and playings
-func (p *FullPerson) SavePerson(db *sql.DB) error {
+func (p *FullPerson) SavePerson(db *sql.DB) (err error) {
 	f := functionSavePerson
 	ctx := context.Background()
 
@@ -165,9 +169,9 @@ func (p *FullPerson) SavePerson(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = p.SavePersonTx(ctx, db)
+	err = p.SavePersonTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -176,14 +180,14 @@ func (p *FullPerson) SavePerson(db *sql.DB) error {
 }
 
 // SavePersonTx writes a new Person to disk and returns the generated id
-func (p *FullPerson) SavePersonTx(ctx context.Context, db *sql.DB) error {
+func (p *FullPerson) SavePersonTx(ctx context.Context, db Querier) error {
 	f := functionSavePersonTx
 
 	fields := "firstname, lastname, knownas, email, phone, hash, status"
 	values := "$1, $2, $3, $4, $5, $6, $7"
-	sqlStatement := "INSERT INTO " + PersonTable + " (" + fields + ") VALUES (" + values + ") RETURNING id"
+	sqlStatement := "INSERT INTO " + PersonTable + " (" + fields + ") VALUES (" + values + ") RETURNING id, rating"
 
-	err := db.QueryRowContext(ctx, sqlStatement, p.FirstName, p.LastName, p.Knownas, p.Email, p.Phone, hex.EncodeToString(p.Hash), p.Status).Scan(&p.ID)
+	err := db.QueryRowContext(ctx, sqlStatement, p.FirstName, p.LastName, p.Knownas, p.Email, p.Phone, hex.EncodeToString(p.Hash), p.Status).Scan(&p.ID, &p.Rating)
 	if err != nil {
 		pgerr, ok := err.(*pgconn.PgError)
 		if ok {
@@ -201,7 +205,7 @@ func (p *FullPerson) SavePersonTx(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
-func (p *FullPerson) UpdatePerson(ctx context.Context, db *sql.DB) error {
+func (p *FullPerson) UpdatePerson(ctx context.Context, db Querier) error {
 	f := functionUpdatePerson
 
 	fields := "firstname=$1, lastname=$2, knownas=$3, email=$4, phone=$5, hash=$6, status=$7"
@@ -217,7 +221,7 @@ func (p *FullPerson) UpdatePerson(ctx context.Context, db *sql.DB) error {
 }
 
 // LoadPerson returns the Person with the given ID
-func (p *FullPerson) LoadPerson(db *sql.DB) error {
+func (p *FullPerson) LoadPerson(db *sql.DB) (err error) {
 	f := functionLoadPerson
 	ctx := context.Background()
 
@@ -228,9 +232,9 @@ func (p *FullPerson) LoadPerson(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = p.LoadPersonTx(ctx, db)
+	err = p.LoadPersonTx(ctx, tx)
 	if err != nil {
 		return err
 	}
@@ -238,11 +242,11 @@ func (p *FullPerson) LoadPerson(db *sql.DB) error {
 	return nil
 }
 
-func (p *FullPerson) LoadPersonTx(ctx context.Context, db *sql.DB) error {
+func (p *FullPerson) LoadPersonTx(ctx context.Context, db Querier) error {
 	f := functionLoadPersonTx
 
 	// Query the person
-	fields := "firstname, lastname, knownas, email, phone, hash, status"
+	fields := "firstname, lastname, knownas, email, phone, hash, status, rating"
 	sqlStatement := "SELECT " + fields + " FROM " + PersonTable + " WHERE id=$1"
 	rows, err := db.QueryContext(ctx, sqlStatement, p.ID)
 	if err != nil {
@@ -257,7 +261,7 @@ func (p *FullPerson) LoadPersonTx(ctx context.Context, db *sql.DB) error {
 		count++
 
 		var np NullPerson
-		err := rows.Scan(&np.FirstName, &np.LastName, &np.Knownas, &np.Email, &np.Phone, &np.Hash, &np.Status)
+		err := rows.Scan(&np.FirstName, &np.LastName, &np.Knownas, &np.Email, &np.Phone, &np.Hash, &np.Status, &np.Rating)
 		if err != nil {
 			message := "Could not scan the person"
 			f.DumpError(err, message)
@@ -296,6 +300,10 @@ func (p *FullPerson) LoadPersonTx(ctx context.Context, db *sql.DB) error {
 		if np.Status.Valid {
 			p.Status = np.Status.String
 		}
+
+		if np.Rating.Valid {
+			p.Rating = int(np.Rating.Int32)
+		}
 	}
 	err = rows.Err()
 	if err != nil {
@@ -318,7 +326,7 @@ func (p *FullPerson) LoadPersonTx(ctx context.Context, db *sql.DB) error {
 }
 
 // DeletePerson removes a person and associated waiters and playings
-func (p *FullPerson) DeletePerson(db *sql.DB) error {
+func (p *FullPerson) DeletePerson(db *sql.DB) (err error) {
 	f := functionDeletePerson
 	ctx := context.Background()
 
@@ -329,9 +337,9 @@ func (p *FullPerson) DeletePerson(db *sql.DB) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = DeletePersonTx(ctx, db, p.ID)
+	err = DeletePersonTx(ctx, tx, p.ID)
 	if err != nil {
 		return err
 	}
@@ -339,7 +347,7 @@ func (p *FullPerson) DeletePerson(db *sql.DB) error {
 	return nil
 }
 
-func DeletePersonTx(ctx context.Context, db *sql.DB, personID int) error {
+func DeletePersonTx(ctx context.Context, db Querier, personID int) error {
 	f := functionDeletePersonTx
 
 	// Remove the associated waiters
@@ -351,6 +359,11 @@ func DeletePersonTx(ctx context.Context, db *sql.DB, personID int) error {
 		return err
 	}
 
+	err = endGameOfTx(ctx, db, personID)
+	if err != nil {
+		return err
+	}
+
 	// Remove the associated playing
 	sqlStatement = "DELETE FROM " + PlayingTable + " WHERE person=" + strconv.Itoa(personID)
 	_, err = db.ExecContext(ctx, sqlStatement)
@@ -373,11 +386,11 @@ func DeletePersonTx(ctx context.Context, db *sql.DB, personID int) error {
 }
 
 // FindPersonByEmail function
-func FindPersonByEmail(ctx context.Context, db *sql.DB, email string) (*FullPerson, error) {
+func FindPersonByEmail(ctx context.Context, db Querier, email string) (*FullPerson, error) {
 	f := functionFindPersonByEmail
 
 	// Query the people
-	fields := "id, firstname, lastname, knownas, email, phone, hash, status"
+	fields := "id, firstname, lastname, knownas, email, phone, hash, status, rating"
 	where := `email=$1`
 	sqlStatement := `SELECT ` + fields + ` FROM ` + PersonTable + ` WHERE ` + where
 
@@ -395,7 +408,7 @@ func FindPersonByEmail(ctx context.Context, db *sql.DB, email string) (*FullPers
 
 		var p FullPerson
 		var hexstring string
-		err := rows.Scan(&p.ID, &p.FirstName, &p.LastName, &p.Knownas, &p.Email, &p.Phone, &hexstring, &p.Status)
+		err := rows.Scan(&p.ID, &p.FirstName, &p.LastName, &p.Knownas, &p.Email, &p.Phone, &hexstring, &p.Status, &p.Rating)
 		if err != nil {
 			message := "Could not scan the person"
 			f.DumpError(err, message)
@@ -441,7 +454,7 @@ func FindPersonByEmail(ctx context.Context, db *sql.DB, email string) (*FullPers
 }
 
 // ListPeople function
-func ListPeople(db *sql.DB, whereClause string) ([]FullPerson, error) {
+func ListPeople(db *sql.DB, whereClause string) (listOfPeople []FullPerson, err error) {
 	f := functionListPeople
 	ctx := context.Background()
 
@@ -452,9 +465,9 @@ func ListPeople(db *sql.DB, whereClause string) ([]FullPerson, error) {
 		f.DumpError(err, message)
 		return nil, err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	listOfPeople, err := ListPeopleTx(ctx, db, whereClause)
+	listOfPeople, err = ListPeopleTx(ctx, tx, whereClause)
 	if err != nil {
 		return nil, err
 	}
@@ -463,11 +476,11 @@ func ListPeople(db *sql.DB, whereClause string) ([]FullPerson, error) {
 }
 
 // ListPeopleTx returns a list of the people IDs
-func ListPeopleTx(ctx context.Context, db *sql.DB, whereClause string) ([]FullPerson, error) {
+func ListPeopleTx(ctx context.Context, db Querier, whereClause string) ([]FullPerson, error) {
 	f := functionListPeopleTx
 
 	// Query the people
-	fields := "id, firstname, lastname, knownas, email, phone, hash, status"
+	fields := "id, firstname, lastname, knownas, email, phone, hash, status, rating"
 	sqlStatement := `SELECT ` + fields + ` FROM ` + PersonTable + ` ` + whereClause + ` ORDER BY ` + `knownas`
 	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
@@ -482,7 +495,7 @@ func ListPeopleTx(ctx context.Context, db *sql.DB, whereClause string) ([]FullPe
 
 		var p FullPerson
 		var hexstring string
-		err := rows.Scan(&p.ID, &p.FirstName, &p.LastName, &p.Knownas, &p.Email, &p.Phone, &hexstring, &p.Status)
+		err := rows.Scan(&p.ID, &p.FirstName, &p.LastName, &p.Knownas, &p.Email, &p.Phone, &hexstring, &p.Status, &p.Rating)
 		if err != nil {
 			message := "Could not scan the person"
 			f.DumpError(err, message)
@@ -612,6 +625,69 @@ func (p *FullPerson) CanGetMetrics() error {
 	return fmt.Errorf("not Authorized")
 }
 
+// CanRepublish checks the user is allowed to republish all the publications
+func (p *FullPerson) CanRepublish() error {
+
+	if p.Status == StatusAdmin {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
+// CanRecalculateRatings checks the user is allowed to replay the history of results
+func (p *FullPerson) CanRecalculateRatings() error {
+
+	if p.Status == StatusAdmin {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
+// CanManageSession checks the user is allowed to open and close a session
+func (p *FullPerson) CanManageSession() error {
+
+	if p.Status == StatusAdmin {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
+// CanCheckIn checks the user is allowed to check themself in or out, or sit out
+func (p *FullPerson) CanCheckIn() error {
+
+	if p.Status == StatusPlayer {
+		return nil
+	}
+	if p.Status == StatusInactive {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
+// CanCheckInOtherPeople checks the user is allowed to check other people in or out, or sit them out
+func (p *FullPerson) CanCheckInOtherPeople() error {
+
+	if p.Status == StatusAdmin {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
+// CanReorderWaiters checks the user is allowed to change the order of the waiting list
+func (p *FullPerson) CanReorderWaiters() error {
+
+	if p.Status == StatusAdmin {
+		return nil
+	}
+
+	return fmt.Errorf("not Authorized")
+}
+
 // CanEditOtherPeople checks the user is allowed update a court
 func (p *FullPerson) CanEditOtherPeople() error {
 
@@ -654,6 +730,7 @@ func (p *FullPerson) ToLimited() *Person {
 		Email:     p.Email,
 		Phone:     p.Phone,
 		Status:    p.Status,
+		Rating:    p.Rating,
 	}
 	return lp
 }
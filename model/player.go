@@ -2,7 +2,6 @@ package model
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
@@ -30,7 +29,7 @@ var (
 )
 
 // AddPlayer
-func AddPlayer(ctx context.Context, db *sql.DB, personID int, courtID int, position int) error {
+func AddPlayer(ctx context.Context, db Querier, personID int, courtID int, position int) error {
 	f := functionAddPlayer
 
 	fields := "person, court, position"
@@ -58,7 +57,7 @@ func AddPlayer(ctx context.Context, db *sql.DB, personID int, courtID int, posit
 }
 
 // RemovePlayer
-func RemovePlayer(ctx context.Context, db *sql.DB, personID int) error {
+func RemovePlayer(ctx context.Context, db Querier, personID int) error {
 	f := functionRemovePlayer
 
 	sqlStatement := "DELETE FROM " + PlayingTable + " WHERE person=$1"
@@ -75,13 +74,13 @@ func RemovePlayer(ctx context.Context, db *sql.DB, personID int) error {
 }
 
 // ListPlayers
-func ListPlayers(ctx context.Context, db *sql.DB) ([]Player, error) {
+func ListPlayers(ctx context.Context, db Querier) ([]Player, error) {
 	f := functionListPlayers
 
 	fields := "court, person, position"
 	sqlStatement := "SELECT " + fields + " FROM " + PlayingTable
 
-	rows, err := db.Query(sqlStatement)
+	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not list the players"
 		f.Errorf(message)
@@ -116,7 +115,7 @@ func ListPlayers(ctx context.Context, db *sql.DB) ([]Player, error) {
 }
 
 // ListPlayersForPerson
-func ListPlayersForPerson(ctx context.Context, db *sql.DB, personID int) ([]Player, error) {
+func ListPlayersForPerson(ctx context.Context, db Querier, personID int) ([]Player, error) {
 	f := functionListPlayersForPerson
 
 	fields := "court, person, position"
@@ -157,7 +156,7 @@ func ListPlayersForPerson(ctx context.Context, db *sql.DB, personID int) ([]Play
 }
 
 // GetPlayersForCourtAsMap
-func GetPlayersForCourtAsMap(ctx context.Context, db *sql.DB, courtID int) (map[int]Player, error) {
+func GetPlayersForCourtAsMap(ctx context.Context, db Querier, courtID int) (map[int]Player, error) {
 	f := functionGetPlayersForCourtAsMap
 	f.DebugVerbose("")
 
@@ -176,7 +175,7 @@ func GetPlayersForCourtAsMap(ctx context.Context, db *sql.DB, courtID int) (map[
 }
 
 // ListPlayersForCourt
-func ListPlayersForCourt(ctx context.Context, db *sql.DB, courtID int) ([]Player, error) {
+func ListPlayersForCourt(ctx context.Context, db Querier, courtID int) ([]Player, error) {
 	f := functionListPlayersForCourt
 
 	fields := "person, court, position"
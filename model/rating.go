@@ -0,0 +1,371 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// LeaderboardEntry type
+type LeaderboardEntry struct {
+	Rank     int      `json:"rank"`
+	PersonId PersonId `json:"personId"`
+	Rating   int      `json:"rating"`
+	Games    int      `json:"games"`
+}
+
+const (
+	// InitialRating is the rating of a person before their first scored game
+	InitialRating = 1500
+
+	// RatingK is the largest change in rating from one game
+	RatingK = 32
+
+	// ratingsLock is the advisory lock which serialises changes to the ratings, so that scores
+	// recorded at the same time as a recalculation are applied in order
+	ratingsLock = 7305
+)
+
+var (
+	functionRecalculateRatings   = debug.NewFunction(pkg, "RecalculateRatings")
+	functionRecalculateRatingsTx = debug.NewFunction(pkg, "RecalculateRatingsTx")
+	functionUpdateRatingsTx      = debug.NewFunction(pkg, "updateRatingsTx")
+	functionLockRatingsTx        = debug.NewFunction(pkg, "lockRatingsTx")
+	functionListLeaderboard      = debug.NewFunction(pkg, "ListLeaderboard")
+	functionListLeaderboardTx    = debug.NewFunction(pkg, "ListLeaderboardTx")
+)
+
+// RatingChange returns the points team 1 gains from a game, which team 2 loses, using the Elo
+// formula on the ratings of the two teams
+func RatingChange(rating1 float64, rating2 float64, winner int) int {
+
+	var actual float64
+	switch winner {
+	case 1:
+		actual = 1
+	case 2:
+		actual = 0
+	default:
+		return 0
+	}
+
+	expected := 1 / (1 + math.Pow(10, (rating2-rating1)/400))
+	return int(math.Round(RatingK * (actual - expected)))
+}
+
+// RateGame returns the change in rating of each person in a scored game on a court of the given
+// capacity. The rating of a team is the average of its players, and each player in the team gains or
+// loses the same points. A person missing from the ratings has the initial rating
+func RateGame(capacity int, positions []Position, sets []SetScore, ratings map[int]int) map[int]int {
+
+	total := map[int]int{}
+	count := map[int]int{}
+	for _, position := range positions {
+		rating, ok := ratings[position.PersonId.ID]
+		if !ok {
+			rating = InitialRating
+		}
+		team := Team(position.Index, capacity)
+		total[team] += rating
+		count[team]++
+	}
+
+	changes := map[int]int{}
+	if count[1] == 0 || count[2] == 0 {
+		return changes
+	}
+
+	rating1 := float64(total[1]) / float64(count[1])
+	rating2 := float64(total[2]) / float64(count[2])
+	change := RatingChange(rating1, rating2, Winner(sets))
+
+	for _, position := range positions {
+		if Team(position.Index, capacity) == 1 {
+			changes[position.PersonId.ID] = change
+		} else {
+			changes[position.PersonId.ID] = -change
+		}
+	}
+
+	return changes
+}
+
+// updateRatingsTx applies the result of a newly scored game to the ratings of its players. When the
+// game had a score before, or a later game is already scored, the whole history is replayed instead
+func updateRatingsTx(ctx context.Context, db Querier, game *Game, rescored bool) error {
+	f := functionUpdateRatingsTx
+
+	err := lockRatingsTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	replay := rescored
+	if !replay {
+		sqlStatement := `
+			SELECT EXISTS (
+				SELECT 1 FROM ` + GameTable + `
+				WHERE (finish, id) > ($1, $2) AND id IN (SELECT game FROM ` + ScoreTable + `)
+			)`
+		err = db.QueryRowContext(ctx, sqlStatement, game.Finish, game.ID).Scan(&replay)
+		if err != nil {
+			message := "Could not look for later scored games"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return err
+		}
+	}
+
+	if replay {
+		_, err = RecalculateRatingsTx(ctx, db)
+		return err
+	}
+
+	ratings := map[int]int{}
+	sqlStatement := "SELECT rating FROM " + PersonTable + " WHERE id=$1"
+	for _, position := range game.Positions {
+		var rating int
+		err = db.QueryRowContext(ctx, sqlStatement, position.PersonId.ID).Scan(&rating)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			message := "Could not load the rating"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return err
+		}
+		ratings[position.PersonId.ID] = rating
+	}
+
+	changes := RateGame(game.Capacity, game.Positions, game.Scores, ratings)
+
+	sqlStatement = "UPDATE " + PersonTable + " SET rating = rating + $1 WHERE id=$2"
+	for personID := range ratings {
+		_, err = db.ExecContext(ctx, sqlStatement, changes[personID], personID)
+		if err != nil {
+			message := "Could not update the rating"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecalculateRatings replays every scored game and returns how many were replayed
+func RecalculateRatings(db *sql.DB) (count int, err error) {
+	f := functionRecalculateRatings
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return 0, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	count, err = RecalculateRatingsTx(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RecalculateRatingsTx starts everyone at the initial rating and replays the scored games in the order
+// they finished, so the result depends only on the history. A person who has since been deleted
+// counts at the initial rating
+func RecalculateRatingsTx(ctx context.Context, db Querier) (int, error) {
+	f := functionRecalculateRatingsTx
+
+	err := lockRatingsTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	ratings := map[int]int{}
+
+	sqlStatement := "SELECT id FROM " + PersonTable
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the people"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var personID int
+		err := rows.Scan(&personID)
+		if err != nil {
+			message := "Could not scan the person"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return 0, err
+		}
+		ratings[personID] = InitialRating
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the people"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+	rows.Close()
+
+	sqlStatement = "SELECT id, capacity FROM " + GameTable + " WHERE id IN (SELECT game FROM " + ScoreTable + ") ORDER BY finish, id"
+	rows, err = db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the scored games"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+	defer rows.Close()
+
+	games := []Game{}
+	for rows.Next() {
+		var game Game
+		err := rows.Scan(&game.ID, &game.Capacity)
+		if err != nil {
+			message := "Could not scan the game"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return 0, err
+		}
+		games = append(games, game)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the scored games"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+
+	rows.Close()
+
+	for _, game := range games {
+		positions, err := loadGamePeople(ctx, db, game.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		sets, err := ListScoresTx(ctx, db, game.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		for personID, change := range RateGame(game.Capacity, positions, sets, ratings) {
+			if _, ok := ratings[personID]; ok {
+				ratings[personID] += change
+			}
+		}
+	}
+
+	sqlStatement = "UPDATE " + PersonTable + " SET rating = $1 WHERE id=$2"
+	for personID, rating := range ratings {
+		_, err = db.ExecContext(ctx, sqlStatement, rating, personID)
+		if err != nil {
+			message := "Could not update the rating"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return 0, err
+		}
+	}
+
+	return len(games), nil
+}
+
+func lockRatingsTx(ctx context.Context, db Querier) error {
+	f := functionLockRatingsTx
+
+	sqlStatement := "SELECT pg_advisory_xact_lock($1)"
+	_, err := db.ExecContext(ctx, sqlStatement, ratingsLock)
+	if err != nil {
+		message := "Could not lock the ratings"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	return nil
+}
+
+// ListLeaderboard returns the people who have played a scored game, highest rating first
+func ListLeaderboard(db *sql.DB) (list []LeaderboardEntry, err error) {
+	f := functionListLeaderboard
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, err = ListLeaderboardTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListLeaderboardTx returns the people who have played a scored game, highest rating first. People
+// with the same rating share a rank
+func ListLeaderboardTx(ctx context.Context, db Querier) ([]LeaderboardEntry, error) {
+	f := functionListLeaderboardTx
+
+	sqlStatement := `
+		SELECT p.id, p.knownas, p.rating, COUNT(DISTINCT g.game)
+		FROM ` + PersonTable + ` p JOIN ` + GamePlayerTable + ` g ON g.person = p.id
+		WHERE g.game IN (SELECT game FROM ` + ScoreTable + `)
+		GROUP BY p.id, p.knownas, p.rating
+		ORDER BY p.rating DESC, p.knownas, p.id`
+
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the leaderboard"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []LeaderboardEntry{}
+	for rows.Next() {
+
+		var entry LeaderboardEntry
+		err := rows.Scan(&entry.PersonId.ID, &entry.PersonId.Knownas, &entry.Rating, &entry.Games)
+		if err != nil {
+			message := "Could not scan the leaderboard"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		entry.Rank = len(list) + 1
+		if len(list) > 0 && list[len(list)-1].Rating == entry.Rating {
+			entry.Rank = list[len(list)-1].Rank
+		}
+
+		list = append(list, entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the leaderboard"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return list, nil
+}
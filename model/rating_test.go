@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestRatingChange(t *testing.T) {
+
+	cases := []struct {
+		rating1 float64
+		rating2 float64
+		winner  int
+		change  int
+	}{
+		{1500, 1500, 1, 16},
+		{1500, 1500, 2, -16},
+		{1500, 1500, 0, 0},
+		{1900, 1500, 1, 3},
+		{1900, 1500, 2, -29},
+	}
+
+	for _, c := range cases {
+		change := RatingChange(c.rating1, c.rating2, c.winner)
+		if change != c.change {
+			t.Logf("Unexpected change: %v v %v, winner %d: expected %d, got %d", c.rating1, c.rating2, c.winner, c.change, change)
+			t.FailNow()
+		}
+	}
+}
+
+func TestRateGame(t *testing.T) {
+
+	positions := []Position{
+		{Index: 0, PersonId: PersonId{ID: 1}},
+		{Index: 1, PersonId: PersonId{ID: 2}},
+		{Index: 2, PersonId: PersonId{ID: 3}},
+		{Index: 3, PersonId: PersonId{ID: 4}},
+	}
+
+	// person 4 is missing, so counts at the initial rating. The teams average 1700 and 1600
+	ratings := map[int]int{1: 1900, 2: 1500, 3: 1700}
+	sets := []SetScore{{5, 11}, {9, 11}, {11, 13}}
+
+	changes := RateGame(DoublesCapacity, positions, sets, ratings)
+
+	expected := RatingChange(1700, 1600, 2)
+	if changes[1] != expected || changes[2] != expected || changes[3] != -expected || changes[4] != -expected {
+		t.Logf("Unexpected changes: %v, expected %d for the first team", changes, expected)
+		t.FailNow()
+	}
+
+	if expected >= -16 {
+		t.Logf("The upset should cost the stronger team more than an even game: %d", expected)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,295 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// SetScore type holds the points of each team in one set
+type SetScore struct {
+	Team1 int `json:"team1"`
+	Team2 int `json:"team2"`
+}
+
+const (
+	// ScoreTable is the name of the score table
+	ScoreTable = "score"
+
+	// PointsToWinSet is the number of points needed to win a set, with a lead of two
+	PointsToWinSet = 11
+)
+
+var (
+	functionRecordScore       = debug.NewFunction(pkg, "RecordScore")
+	functionRecordScoreTx     = debug.NewFunction(pkg, "RecordScoreTx")
+	functionLoadGameTx        = debug.NewFunction(pkg, "LoadGameTx")
+	functionLatestGameOnCourt = debug.NewFunction(pkg, "LatestGameOnCourtTx")
+	functionListScoresTx      = debug.NewFunction(pkg, "ListScoresTx")
+)
+
+// Team returns the team of a position: the first half of the court against the second half
+func Team(index int, capacity int) int {
+	if index < capacity/2 {
+		return 1
+	}
+	return 2
+}
+
+// ValidateScore checks the sets follow the table-tennis rules for a match which is the best of
+// 'bestOf' sets, and that the game has a player in each team
+func ValidateScore(capacity int, positions []Position, sets []SetScore, bestOf int) error {
+
+	if bestOf != 3 && bestOf != 5 && bestOf != 7 {
+		return codeerror.NewBadRequest(fmt.Sprintf("a match is the best of 3, 5 or 7 sets, not %d", bestOf))
+	}
+
+	teams := map[int]bool{}
+	for _, position := range positions {
+		teams[Team(position.Index, capacity)] = true
+	}
+	if !teams[1] || !teams[2] {
+		return codeerror.NewBadRequest("the game does not have a player in each team")
+	}
+
+	if len(sets) == 0 {
+		return codeerror.NewBadRequest("there are no sets in the score")
+	}
+
+	setsToWin := bestOf/2 + 1
+	won := map[int]int{}
+
+	for i, set := range sets {
+		number := i + 1
+
+		if won[1] == setsToWin || won[2] == setsToWin {
+			return codeerror.NewBadRequest(fmt.Sprintf("set %d was played after the match was won", number))
+		}
+
+		err := validateSet(number, set)
+		if err != nil {
+			return err
+		}
+
+		if set.Team1 > set.Team2 {
+			won[1]++
+		} else {
+			won[2]++
+		}
+	}
+
+	if won[1] < setsToWin && won[2] < setsToWin {
+		return codeerror.NewBadRequest(fmt.Sprintf("the match is not finished: %d-%d in sets, %d needed to win", won[1], won[2], setsToWin))
+	}
+
+	return nil
+}
+
+func validateSet(number int, set SetScore) error {
+
+	if set.Team1 < 0 || set.Team2 < 0 {
+		return codeerror.NewBadRequest(fmt.Sprintf("set %d has a negative score: %d-%d", number, set.Team1, set.Team2))
+	}
+
+	winner, loser := set.Team1, set.Team2
+	if loser > winner {
+		winner, loser = loser, winner
+	}
+
+	if winner == loser {
+		return codeerror.NewBadRequest(fmt.Sprintf("set %d is drawn: %d-%d", number, set.Team1, set.Team2))
+	}
+
+	if winner < PointsToWinSet {
+		return codeerror.NewBadRequest(fmt.Sprintf("set %d is not finished: %d-%d, the winner needs %d points", number, set.Team1, set.Team2, PointsToWinSet))
+	}
+
+	if loser <= PointsToWinSet-2 {
+		if winner != PointsToWinSet {
+			return codeerror.NewBadRequest(fmt.Sprintf("set %d went on after it was won: %d-%d, the set ends at %d", number, set.Team1, set.Team2, PointsToWinSet))
+		}
+		return nil
+	}
+
+	if winner-loser < 2 {
+		return codeerror.NewBadRequest(fmt.Sprintf("set %d is not finished: %d-%d, the winner needs a lead of 2", number, set.Team1, set.Team2))
+	}
+
+	if winner-loser > 2 {
+		return codeerror.NewBadRequest(fmt.Sprintf("set %d went on after it was won: %d-%d, a set past %d-%d ends with a lead of 2", number, set.Team1, set.Team2, PointsToWinSet-1, PointsToWinSet-1))
+	}
+
+	return nil
+}
+
+// Winner returns the team which won the most sets, or 0 if there is no score
+func Winner(sets []SetScore) int {
+
+	won := map[int]int{}
+	for _, set := range sets {
+		if set.Team1 > set.Team2 {
+			won[1]++
+		} else if set.Team2 > set.Team1 {
+			won[2]++
+		}
+	}
+
+	if won[1] > won[2] {
+		return 1
+	} else if won[2] > won[1] {
+		return 2
+	}
+	return 0
+}
+
+// RecordScore validates the score of a game and stores it, replacing any earlier score
+func RecordScore(db *sql.DB, gameID int, sets []SetScore, bestOf int) (err error) {
+	f := functionRecordScore
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = RecordScoreTx(ctx, tx, gameID, sets, bestOf)
+	return err
+}
+
+// RecordScoreTx validates the score of a game and stores it, replacing any earlier score, then updates
+// the ratings of the players
+func RecordScoreTx(ctx context.Context, db Querier, gameID int, sets []SetScore, bestOf int) error {
+	f := functionRecordScoreTx
+
+	game, err := LoadGameTx(ctx, db, gameID)
+	if err != nil {
+		return err
+	}
+
+	err = ValidateScore(game.Capacity, game.Positions, sets, bestOf)
+	if err != nil {
+		f.DebugVerbose(err.Error())
+		return err
+	}
+
+	rescored := len(game.Scores) > 0
+
+	sqlStatement := "DELETE FROM " + ScoreTable + " WHERE game=$1"
+	_, err = db.ExecContext(ctx, sqlStatement, gameID)
+	if err != nil {
+		message := "Could not delete the previous score"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	sqlStatement = "INSERT INTO " + ScoreTable + " (game, set_number, team1, team2) VALUES ($1, $2, $3, $4)"
+	for i, set := range sets {
+		_, err = db.ExecContext(ctx, sqlStatement, gameID, i+1, set.Team1, set.Team2)
+		if err != nil {
+			message := "Could not insert into " + ScoreTable
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return err
+		}
+	}
+
+	game.Scores = sets
+	return updateRatingsTx(ctx, db, game, rescored)
+}
+
+// LoadGameTx returns a game with its positions and score
+func LoadGameTx(ctx context.Context, db Querier, gameID int) (*Game, error) {
+	f := functionLoadGameTx
+
+	sqlStatement := "SELECT id, court, court_name, COALESCE(session, 0), capacity, start, finish FROM " + GameTable + " WHERE id=$1"
+
+	game := new(Game)
+	err := db.QueryRowContext(ctx, sqlStatement, gameID).Scan(&game.ID, &game.Court, &game.CourtName, &game.Session, &game.Capacity, &game.Start, &game.Finish)
+	if err == sql.ErrNoRows {
+		return nil, codeerror.NewNotFound(fmt.Sprintf("game [%d] not found", gameID))
+	} else if err != nil {
+		message := "Could not load the game"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	game.Positions, err = loadGamePeople(ctx, db, game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	game.Scores, err = ListScoresTx(ctx, db, game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+// LatestGameOnCourtTx returns the id of the most recent game on the court
+func LatestGameOnCourtTx(ctx context.Context, db Querier, courtID int) (int, error) {
+	f := functionLatestGameOnCourt
+
+	sqlStatement := "SELECT id FROM " + GameTable + " WHERE court=$1 ORDER BY finish DESC, id DESC LIMIT 1"
+
+	var gameID int
+	err := db.QueryRowContext(ctx, sqlStatement, courtID).Scan(&gameID)
+	if err == sql.ErrNoRows {
+		return 0, codeerror.NewNotFound(fmt.Sprintf("there are no games on court [%d]", courtID))
+	} else if err != nil {
+		message := "Could not find the latest game"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+
+	return gameID, nil
+}
+
+// ListScoresTx returns the sets of a game in order
+func ListScoresTx(ctx context.Context, db Querier, gameID int) ([]SetScore, error) {
+	f := functionListScoresTx
+
+	sqlStatement := "SELECT team1, team2 FROM " + ScoreTable + " WHERE game=$1 ORDER BY set_number"
+
+	rows, err := db.QueryContext(ctx, sqlStatement, gameID)
+	if err != nil {
+		message := "Could not list the scores"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []SetScore{}
+	for rows.Next() {
+
+		var set SetScore
+		err := rows.Scan(&set.Team1, &set.Team2)
+		if err != nil {
+			message := "Could not scan the score"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		sets = append(sets, set)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the scores"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return sets, nil
+}
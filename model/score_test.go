@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestValidateScore(t *testing.T) {
+
+	doubles := []Position{{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}}
+	singles := []Position{{Index: 0}, {Index: 2}}
+	oneTeam := []Position{{Index: 0}, {Index: 1}}
+
+	cases := []struct {
+		positions []Position
+		sets      []SetScore
+		bestOf    int
+		valid     bool
+	}{
+		{doubles, []SetScore{{11, 5}, {11, 9}, {11, 0}}, 5, true},
+		{doubles, []SetScore{{11, 5}, {9, 11}, {12, 10}, {5, 11}, {14, 12}}, 5, true},
+		{singles, []SetScore{{11, 5}, {11, 9}}, 3, true},
+		{doubles, []SetScore{{11, 5}, {11, 9}, {11, 0}, {11, 3}}, 7, true},
+		{oneTeam, []SetScore{{11, 5}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{11, 5}, {11, 9}, {11, 0}}, 4, false},
+		{doubles, []SetScore{}, 5, false},
+		{doubles, []SetScore{{11, 5}, {11, 9}}, 5, false},
+		{doubles, []SetScore{{11, 5}, {11, 9}, {11, 0}, {11, 3}}, 5, false},
+		{doubles, []SetScore{{10, 5}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{12, 5}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{11, 10}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{15, 10}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{11, 11}, {11, 9}, {11, 0}}, 5, false},
+		{doubles, []SetScore{{-1, 11}, {11, 9}, {11, 0}}, 5, false},
+	}
+
+	for i, c := range cases {
+		err := ValidateScore(DoublesCapacity, c.positions, c.sets, c.bestOf)
+		if c.valid && err != nil {
+			t.Logf("case %d: unexpected error: %s", i, err)
+			t.FailNow()
+		}
+		if !c.valid && err == nil {
+			t.Logf("case %d: expected an error: %v", i, c.sets)
+			t.FailNow()
+		}
+	}
+
+	// on a singles court the second position is in the other team
+	err := ValidateScore(SinglesCapacity, oneTeam, []SetScore{{11, 5}, {11, 9}}, 3)
+	if err != nil {
+		t.Logf("unexpected error for singles: %s", err)
+		t.FailNow()
+	}
+}
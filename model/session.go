@@ -0,0 +1,316 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Session type is a club night. Courts and the waiting list are only used while a session is open. A
+// game is limited to GameMinutes, unless its court has a limit of its own
+type Session struct {
+	ID          int        `json:"id"`
+	Opened      time.Time  `json:"opened"`
+	Closed      *time.Time `json:"closed"`
+	GameMinutes int        `json:"gameMinutes"`
+	Games       int        `json:"games"`
+	Attendance  []PersonId `json:"attendance"`
+}
+
+const (
+	// SessionTable is the name of the session table
+	SessionTable = "session"
+
+	// AttendanceTable is the name of the table of the people who came to each session
+	AttendanceTable = "attendance"
+)
+
+var (
+	functionOpenSession          = debug.NewFunction(pkg, "OpenSession")
+	functionOpenSessionTx        = debug.NewFunction(pkg, "OpenSessionTx")
+	functionCloseSession         = debug.NewFunction(pkg, "CloseSession")
+	functionCloseSessionTx       = debug.NewFunction(pkg, "CloseSessionTx")
+	functionCurrentSessionTx     = debug.NewFunction(pkg, "CurrentSessionTx")
+	functionLoadSessionTx        = debug.NewFunction(pkg, "LoadSessionTx")
+	functionRecordAttendanceTx   = debug.NewFunction(pkg, "recordAttendanceTx")
+	functionRequireOpenSessionTx = debug.NewFunction(pkg, "RequireOpenSessionTx")
+)
+
+// OpenSession starts a new session
+func OpenSession(db *sql.DB, gameMinutes int) (session *Session, err error) {
+	f := functionOpenSession
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	session, err = OpenSessionTx(ctx, tx, gameMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// OpenSessionTx starts a new session, where games are limited to the given minutes. Only one session can
+// be open at a time
+func OpenSessionTx(ctx context.Context, db Querier, gameMinutes int) (*Session, error) {
+	f := functionOpenSessionTx
+
+	err := ValidateGameMinutes(gameMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := CurrentSessionTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID > 0 {
+		return nil, codeerror.NewConflict(fmt.Sprintf("session [%d] is already open", sessionID))
+	}
+
+	sqlStatement := "INSERT INTO " + SessionTable + " (opened, game_minutes) VALUES (CURRENT_TIMESTAMP, $1) RETURNING id"
+	err = db.QueryRowContext(ctx, sqlStatement, gameMinutes).Scan(&sessionID)
+	if err != nil {
+		message := "Could not insert into " + SessionTable
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	return LoadSessionTx(ctx, db, sessionID)
+}
+
+// CloseSession ends the open session
+func CloseSession(db *sql.DB) (session *Session, err error) {
+	f := functionCloseSession
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	session, err = CloseSessionTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// CloseSessionTx ends the open session. The games on the courts are recorded, and everyone still
+// playing or waiting is made inactive, which leaves the courts and the waiting list empty. The games
+// and attendance stay with the closed session
+func CloseSessionTx(ctx context.Context, db Querier) (*Session, error) {
+	f := functionCloseSessionTx
+
+	sessionID, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := "SELECT DISTINCT court FROM " + PlayingTable + " ORDER BY court"
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the courts in use"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courts []int
+	for rows.Next() {
+		var courtID int
+		err := rows.Scan(&courtID)
+		if err != nil {
+			message := "Could not scan the court"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+		courts = append(courts, courtID)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the courts in use"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	rows.Close()
+
+	for _, courtID := range courts {
+		err = ClearCourtTx(ctx, db, courtID)
+		if err != nil {
+			message := fmt.Sprintf("Could not clear court [%d]", courtID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+	}
+
+	people, err := ListPeopleTx(ctx, db, "WHERE status = '"+StatusPlayer+"'")
+	if err != nil {
+		message := "Could not list the players"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	for _, person := range people {
+		err = MakePersonInactiveTx(ctx, db, person.ID)
+		if err != nil {
+			message := fmt.Sprintf("Could not make person [%d] inactive", person.ID)
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+	}
+
+	sqlStatement = "UPDATE " + SessionTable + " SET closed = CURRENT_TIMESTAMP WHERE id=$1"
+	_, err = db.ExecContext(ctx, sqlStatement, sessionID)
+	if err != nil {
+		message := "Could not close the session"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	return LoadSessionTx(ctx, db, sessionID)
+}
+
+// CurrentSessionTx returns the id of the open session, or 0 if there is none
+func CurrentSessionTx(ctx context.Context, db Querier) (int, error) {
+	f := functionCurrentSessionTx
+
+	sqlStatement := "SELECT id FROM " + SessionTable + " WHERE closed IS NULL"
+
+	var sessionID int
+	err := db.QueryRowContext(ctx, sqlStatement).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		message := "Could not find the open session"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return 0, err
+	}
+
+	return sessionID, nil
+}
+
+// RequireOpenSessionTx returns the id of the open session, or an error if there is none
+func RequireOpenSessionTx(ctx context.Context, db Querier) (int, error) {
+	f := functionRequireOpenSessionTx
+
+	sessionID, err := CurrentSessionTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	if sessionID <= 0 {
+		message := "there is no open session"
+		f.DebugVerbose(message)
+		return 0, codeerror.NewConflict(message)
+	}
+
+	return sessionID, nil
+}
+
+// LoadSessionTx returns a session with the number of games played and who came
+func LoadSessionTx(ctx context.Context, db Querier, sessionID int) (*Session, error) {
+	f := functionLoadSessionTx
+
+	sqlStatement := `
+		SELECT s.id, s.opened, s.closed, s.game_minutes, (SELECT COUNT(*) FROM ` + GameTable + ` g WHERE g.session = s.id)
+		FROM ` + SessionTable + ` s
+		WHERE s.id = $1`
+
+	session := new(Session)
+	var closed sql.NullTime
+	err := db.QueryRowContext(ctx, sqlStatement, sessionID).Scan(&session.ID, &session.Opened, &closed, &session.GameMinutes, &session.Games)
+	if err == sql.ErrNoRows {
+		return nil, codeerror.NewNotFound(fmt.Sprintf("session [%d] not found", sessionID))
+	} else if err != nil {
+		message := "Could not load the session"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+
+	if closed.Valid {
+		session.Closed = &closed.Time
+	}
+
+	sqlStatement = `
+		SELECT a.person, COALESCE(p.knownas, '')
+		FROM ` + AttendanceTable + ` a LEFT JOIN ` + PersonTable + ` p ON p.id = a.person
+		WHERE a.session = $1
+		ORDER BY a.arrived, a.person`
+
+	rows, err := db.QueryContext(ctx, sqlStatement, sessionID)
+	if err != nil {
+		message := "Could not list the attendance"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	session.Attendance = []PersonId{}
+	for rows.Next() {
+		var personId PersonId
+		err := rows.Scan(&personId.ID, &personId.Knownas)
+		if err != nil {
+			message := "Could not scan the attendance"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+		session.Attendance = append(session.Attendance, personId)
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the attendance"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// recordAttendanceTx notes that the person came to the open session, if there is one
+func recordAttendanceTx(ctx context.Context, db Querier, personID int) error {
+	f := functionRecordAttendanceTx
+
+	sqlStatement := `
+		INSERT INTO ` + AttendanceTable + ` (session, person, arrived)
+		SELECT id, $1, CURRENT_TIMESTAMP FROM ` + SessionTable + ` WHERE closed IS NULL
+		ON CONFLICT DO NOTHING`
+
+	_, err := db.ExecContext(ctx, sqlStatement, personID)
+	if err != nil {
+		message := "Could not record the attendance"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	return nil
+}
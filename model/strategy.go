@@ -0,0 +1,292 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+// Candidate is a person who could be picked for an empty position on a court, or who is already on it
+type Candidate struct {
+	PersonId     PersonId
+	Start        time.Time
+	Rating       int
+	GamesTonight int
+
+	// Partners counts the games tonight the candidate played in the same team as each other person. Tonight
+	// is the open session
+	Partners map[int]int
+}
+
+// Selection records who was picked for a position, and why
+type Selection struct {
+	Index    int      `json:"index"`
+	PersonId PersonId `json:"personId"`
+	Reason   string   `json:"reason"`
+}
+
+// FillStrategy chooses who fills the empty positions of a court
+type FillStrategy interface {
+
+	// Pick returns the index of the candidate for the empty position, given the capacity of the court and
+	// the people already on it by position, and the reason for the choice. The candidates are in queue order
+	Pick(index int, capacity int, court map[int]Candidate, candidates []Candidate) (int, string)
+}
+
+// FirstComeStrategy picks the person at the front of the queue
+type FirstComeStrategy struct{}
+
+// FewestGamesStrategy picks the person who has played the fewest games tonight
+type FewestGamesStrategy struct{}
+
+// AvoidPartnersStrategy picks the person who has partnered the players already in the team the fewest times tonight
+type AvoidPartnersStrategy struct{}
+
+// BalancedStrategy picks the person who brings the average ratings of the two teams closest together
+type BalancedStrategy struct{}
+
+const (
+	// DefaultFillStrategy is used when neither the request nor the configuration names a strategy
+	DefaultFillStrategy = "firstCome"
+)
+
+var (
+	// FillStrategies lists the built-in strategies by name
+	FillStrategies = map[string]FillStrategy{
+		"firstCome":     FirstComeStrategy{},
+		"fewestGames":   FewestGamesStrategy{},
+		"avoidPartners": AvoidPartnersStrategy{},
+		"balanced":      BalancedStrategy{},
+	}
+
+	functionListCandidatesTx = debug.NewFunction(pkg, "ListCandidatesTx")
+	functionLoadTonightTx    = debug.NewFunction(pkg, "loadTonightTx")
+)
+
+// LookupFillStrategy returns the strategy with the given name, or the default strategy if the name is empty
+func LookupFillStrategy(name string) (FillStrategy, error) {
+
+	if name == "" {
+		name = DefaultFillStrategy
+	}
+
+	strategy, ok := FillStrategies[name]
+	if !ok {
+		return nil, codeerror.NewBadRequest(fmt.Sprintf("unknown strategy: '%s'", name))
+	}
+
+	return strategy, nil
+}
+
+// Pick method
+func (s FirstComeStrategy) Pick(index int, capacity int, court map[int]Candidate, candidates []Candidate) (int, string) {
+	return 0, fmt.Sprintf("waiting since %s, the first in the queue", candidates[0].Start.Format("15:04"))
+}
+
+// Pick method
+func (s FewestGamesStrategy) Pick(index int, capacity int, court map[int]Candidate, candidates []Candidate) (int, string) {
+
+	best := 0
+	ties := 0
+	for i, candidate := range candidates {
+		if candidate.GamesTonight < candidates[best].GamesTonight {
+			best = i
+			ties = 1
+		} else if candidate.GamesTonight == candidates[best].GamesTonight {
+			ties++
+		}
+	}
+
+	reason := fmt.Sprintf("played %d games tonight, the fewest of the %d waiting", candidates[best].GamesTonight, len(candidates))
+	if ties > 1 {
+		reason += ", and was the first of those in the queue"
+	}
+	return best, reason
+}
+
+// Pick method
+func (s AvoidPartnersStrategy) Pick(index int, capacity int, court map[int]Candidate, candidates []Candidate) (int, string) {
+
+	partners := []Candidate{}
+	for i := 0; i < capacity; i++ {
+		person, ok := court[i]
+		if ok && Team(i, capacity) == Team(index, capacity) {
+			partners = append(partners, person)
+		}
+	}
+
+	if len(partners) == 0 {
+		return 0, "no partner is on the court yet, so the first in the queue"
+	}
+
+	names := []string{}
+	for _, partner := range partners {
+		names = append(names, partner.PersonId.Knownas)
+	}
+
+	best := 0
+	bestCount := math.MaxInt
+	for i, candidate := range candidates {
+		count := 0
+		for _, partner := range partners {
+			count += candidate.Partners[partner.PersonId.ID]
+		}
+		if count < bestCount {
+			best = i
+			bestCount = count
+		}
+	}
+
+	if bestCount == 0 {
+		return best, fmt.Sprintf("has not partnered %s tonight", strings.Join(names, " or "))
+	}
+	return best, fmt.Sprintf("partnered %s %d times tonight, the fewest of the %d waiting", strings.Join(names, " and "), bestCount, len(candidates))
+}
+
+// Pick method
+func (s BalancedStrategy) Pick(index int, capacity int, court map[int]Candidate, candidates []Candidate) (int, string) {
+
+	total := map[int]int{}
+	count := map[int]int{}
+	for i, person := range court {
+		total[Team(i, capacity)] += person.Rating
+		count[Team(i, capacity)]++
+	}
+
+	team := Team(index, capacity)
+	other := 3 - team
+	if count[other] == 0 {
+		return 0, "the other team is empty, so the first in the queue"
+	}
+
+	otherAverage := float64(total[other]) / float64(count[other])
+
+	best := 0
+	bestGap := math.Inf(1)
+	var bestAverage float64
+	for i, candidate := range candidates {
+		average := float64(total[team]+candidate.Rating) / float64(count[team]+1)
+		gap := math.Abs(average - otherAverage)
+		if gap < bestGap {
+			best = i
+			bestGap = gap
+			bestAverage = average
+		}
+	}
+
+	return best, fmt.Sprintf("rated %d, which makes the teams %.0f against %.0f, the closest of the %d waiting", candidates[best].Rating, bestAverage, otherAverage, len(candidates))
+}
+
+// ListCandidatesTx returns the waiters who are not paused in queue order, with what the strategies need to know about them
+func ListCandidatesTx(ctx context.Context, db Querier) ([]Candidate, error) {
+	f := functionListCandidatesTx
+
+	waiters, err := ListWaitersTx(ctx, db)
+	if err != nil {
+		message := "Could not list the waiters"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	games, partners, err := loadTonightTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []Candidate{}
+	for _, waiter := range waiters {
+		if waiter.Paused {
+			continue
+		}
+
+		person := FullPerson{ID: waiter.Person}
+		err = person.LoadPersonTx(ctx, db)
+		if err != nil {
+			message := "Could not load the waiter"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		candidate := Candidate{
+			PersonId:     PersonId{ID: person.ID, Knownas: person.Knownas},
+			Start:        waiter.Start,
+			Rating:       person.Rating,
+			GamesTonight: games[person.ID],
+			Partners:     partners[person.ID],
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// loadTonightTx counts the games each person has played in the open session, and how often each pair
+// of people were in the same team
+func loadTonightTx(ctx context.Context, db Querier) (map[int]int, map[int]map[int]int, error) {
+	f := functionLoadTonightTx
+
+	sqlStatement := `
+		SELECT a.game, g.capacity, a.person, a.position, b.person, b.position
+		FROM ` + GamePlayerTable + ` a
+		JOIN ` + GameTable + ` g ON g.id = a.game
+		LEFT JOIN ` + GamePlayerTable + ` b ON b.game = a.game AND b.person <> a.person
+		WHERE g.session = (SELECT id FROM ` + SessionTable + ` WHERE closed IS NULL)`
+
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list tonight's games"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	played := map[int]map[int]bool{}
+	partners := map[int]map[int]int{}
+	for rows.Next() {
+
+		var gameID, capacity, person, position int
+		var other, otherPosition sql.NullInt32
+		err := rows.Scan(&gameID, &capacity, &person, &position, &other, &otherPosition)
+		if err != nil {
+			message := "Could not scan tonight's games"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, nil, err
+		}
+
+		if played[person] == nil {
+			played[person] = map[int]bool{}
+		}
+		played[person][gameID] = true
+
+		if other.Valid && Team(position, capacity) == Team(int(otherPosition.Int32), capacity) {
+			if partners[person] == nil {
+				partners[person] = map[int]int{}
+			}
+			partners[person][int(other.Int32)]++
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list tonight's games"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, nil, err
+	}
+
+	games := map[int]int{}
+	for person, list := range played {
+		games[person] = len(list)
+	}
+
+	return games, partners, nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestFillStrategies(t *testing.T) {
+
+	al := Candidate{PersonId: PersonId{ID: 1, Knownas: "Al"}, Rating: 1500, GamesTonight: 3}
+	bo := Candidate{PersonId: PersonId{ID: 2, Knownas: "Bo"}, Rating: 1800, GamesTonight: 1, Partners: map[int]int{10: 2}}
+	cy := Candidate{PersonId: PersonId{ID: 3, Knownas: "Cy"}, Rating: 1300, GamesTonight: 1}
+	candidates := []Candidate{al, bo, cy}
+
+	// Di and Ed are in the first team, Fy is in the second
+	court := map[int]Candidate{
+		0: {PersonId: PersonId{ID: 10, Knownas: "Di"}, Rating: 1600},
+		1: {PersonId: PersonId{ID: 11, Knownas: "Ed"}, Rating: 1400},
+		2: {PersonId: PersonId{ID: 12, Knownas: "Fy"}, Rating: 1700},
+	}
+
+	cases := []struct {
+		name       string
+		index      int
+		court      map[int]Candidate
+		candidates []Candidate
+		expected   int
+	}{
+		{"firstCome", 3, court, candidates, 0},
+		{"fewestGames", 3, court, candidates, 1},
+		{"balanced", 3, court, candidates, 2},
+		{"avoidPartners", 1, map[int]Candidate{0: court[0]}, []Candidate{bo, al, cy}, 1},
+		{"avoidPartners", 1, map[int]Candidate{}, []Candidate{bo, al, cy}, 0},
+	}
+
+	for _, c := range cases {
+		strategy, err := LookupFillStrategy(c.name)
+		if err != nil {
+			t.Logf("Could not find the strategy: %s", c.name)
+			t.FailNow()
+		}
+
+		i, reason := strategy.Pick(c.index, DoublesCapacity, c.court, c.candidates)
+		if i != c.expected {
+			t.Logf("Strategy %s picked %d, expected %d: %s", c.name, i, c.expected, reason)
+			t.FailNow()
+		}
+		if reason == "" {
+			t.Logf("Strategy %s gave no reason", c.name)
+			t.FailNow()
+		}
+	}
+
+	_, err := LookupFillStrategy("unknown")
+	if err == nil {
+		t.Log("Expected an error for an unknown strategy")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
+	"github.com/rsmaxwell/players-tt-api/internal/debug"
+)
+
+const (
+	// MaxGameMinutes is the longest time limit of a game
+	MaxGameMinutes = 180
+)
+
+var (
+	functionListGameDeadlinesTx = debug.NewFunction(pkg, "ListGameDeadlinesTx")
+	functionListExpiredCourts   = debug.NewFunction(pkg, "ListExpiredCourts")
+	functionListExpiredCourtsTx = debug.NewFunction(pkg, "ListExpiredCourtsTx")
+)
+
+// ValidateGameMinutes checks the time limit of a game, where zero means no limit
+func ValidateGameMinutes(minutes int) error {
+
+	if minutes < 0 || minutes > MaxGameMinutes {
+		return codeerror.NewBadRequest(fmt.Sprintf("a game is limited to between 0 and %d minutes, not %d", MaxGameMinutes, minutes))
+	}
+
+	return nil
+}
+
+// GameLimit returns the time limit of a game on a court. The limit of the court overrides the limit
+// of the session, and zero means no limit
+func GameLimit(courtMinutes int, sessionMinutes int) int {
+	if courtMinutes > 0 {
+		return courtMinutes
+	}
+	return sessionMinutes
+}
+
+// Remaining returns the whole seconds from now until the deadline, or zero once it has passed
+func Remaining(deadline time.Time, now time.Time) int {
+	seconds := math.Ceil(deadline.Sub(now).Seconds())
+	if seconds < 0 {
+		return 0
+	}
+	return int(seconds)
+}
+
+// ListGameDeadlinesTx returns when the game on each court runs out of time, for the courts in use which
+// have a time limit. A game starts when the last of its players arrives on the court, and games are
+// only limited while a session is open
+func ListGameDeadlinesTx(ctx context.Context, db Querier) (map[int]time.Time, error) {
+	f := functionListGameDeadlinesTx
+
+	sqlStatement := `
+		SELECT p.court, MAX(p.start), c.game_minutes, s.game_minutes
+		FROM ` + PlayingTable + ` p
+		JOIN ` + CourtTable + ` c ON c.id = p.court
+		CROSS JOIN ` + SessionTable + ` s
+		WHERE s.closed IS NULL
+		GROUP BY p.court, c.game_minutes, s.game_minutes`
+
+	rows, err := db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		message := "Could not list the games in progress"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadlines := map[int]time.Time{}
+	for rows.Next() {
+
+		var courtID, courtMinutes, sessionMinutes int
+		var start time.Time
+		err := rows.Scan(&courtID, &start, &courtMinutes, &sessionMinutes)
+		if err != nil {
+			message := "Could not scan the game in progress"
+			f.Errorf(message)
+			f.DumpError(err, message)
+			return nil, err
+		}
+
+		minutes := GameLimit(courtMinutes, sessionMinutes)
+		if minutes > 0 {
+			deadlines[courtID] = start.Add(time.Duration(minutes) * time.Minute)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		message := "Could not list the games in progress"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, err
+	}
+
+	return deadlines, nil
+}
+
+// ListExpiredCourts returns the courts whose game has run out of time, and whether any game has a limit
+func ListExpiredCourts(db *sql.DB) (list []int, limited bool, err error) {
+	f := functionListExpiredCourts
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return nil, false, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	list, limited, err = ListExpiredCourtsTx(ctx, tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return list, limited, nil
+}
+
+// ListExpiredCourtsTx returns the courts whose game has run out of time, in order, and whether any
+// game has a limit
+func ListExpiredCourtsTx(ctx context.Context, db Querier) ([]int, bool, error) {
+	f := functionListExpiredCourtsTx
+
+	deadlines, err := ListGameDeadlinesTx(ctx, db)
+	if err != nil {
+		message := "Could not list the deadlines of the games"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return nil, false, err
+	}
+
+	now := time.Now()
+	list := []int{}
+	for courtID, deadline := range deadlines {
+		if !deadline.After(now) {
+			list = append(list, courtID)
+		}
+	}
+	sort.Ints(list)
+
+	return list, len(deadlines) > 0, nil
+}
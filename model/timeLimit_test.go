@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameLimit(t *testing.T) {
+
+	cases := []struct {
+		court   int
+		session int
+		limit   int
+	}{
+		{0, 0, 0},
+		{0, 15, 15},
+		{10, 15, 10},
+		{20, 0, 20},
+	}
+
+	for _, c := range cases {
+		limit := GameLimit(c.court, c.session)
+		if limit != c.limit {
+			t.Logf("Unexpected limit for court %d and session %d: expected %d, got %d", c.court, c.session, c.limit, limit)
+			t.FailNow()
+		}
+	}
+
+	for _, minutes := range []int{-1, MaxGameMinutes + 1} {
+		if ValidateGameMinutes(minutes) == nil {
+			t.Logf("Unexpected valid limit: %d", minutes)
+			t.FailNow()
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+
+	now := time.Date(2021, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		deadline  time.Time
+		remaining int
+	}{
+		{now.Add(15 * time.Minute), 900},
+		{now.Add(1500 * time.Millisecond), 2},
+		{now, 0},
+		{now.Add(-time.Minute), 0},
+	}
+
+	for _, c := range cases {
+		remaining := Remaining(c.deadline, now)
+		if remaining != c.remaining {
+			t.Logf("Unexpected remaining seconds until %v: expected %d, got %d", c.deadline, c.remaining, remaining)
+			t.FailNow()
+		}
+	}
+}
@@ -15,7 +15,7 @@ var (
 )
 
 // UpdateCourt method
-func UpdateCourtFields(db *sql.DB, courtID int, fields map[string]interface{}) error {
+func UpdateCourtFields(db *sql.DB, courtID int, fields map[string]interface{}) (err error) {
 	f := functionUpdateCourtFields
 	ctx := context.Background()
 
@@ -26,9 +26,9 @@ func UpdateCourtFields(db *sql.DB, courtID int, fields map[string]interface{}) e
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = UpdateCourtFieldsTx(ctx, db, courtID, fields)
+	err = UpdateCourtFieldsTx(ctx, tx, courtID, fields)
 	if err != nil {
 		return err
 	}
@@ -36,7 +36,7 @@ func UpdateCourtFields(db *sql.DB, courtID int, fields map[string]interface{}) e
 	return nil
 }
 
-func UpdateCourtFieldsTx(ctx context.Context, db *sql.DB, courtID int, fields map[string]interface{}) error {
+func UpdateCourtFieldsTx(ctx context.Context, db Querier, courtID int, fields map[string]interface{}) error {
 	f := functionUpdateCourtFieldsTx
 
 	c := Court{ID: courtID}
@@ -59,6 +59,51 @@ func UpdateCourtFieldsTx(ctx context.Context, db *sql.DB, courtID int, fields ma
 		}
 	}
 
+	if val, ok := fields["capacity"]; ok {
+		capacity, ok := val.(float64)
+		if !ok {
+			message := fmt.Sprintf("unexpected type for [%s]: %v", "capacity", val)
+			f.DebugVerbose(message)
+			return codeerror.NewBadRequest(message)
+		}
+		c.Capacity = int(capacity)
+
+		err = ValidateCapacity(c.Capacity)
+		if err != nil {
+			return err
+		}
+
+		players, err := ListPlayersForCourt(ctx, db, courtID)
+		if err != nil {
+			message := fmt.Sprintf("could not list the players on court: %d", courtID)
+			f.DumpError(err, message)
+			return codeerror.NewInternalServerError(message)
+		}
+
+		for _, player := range players {
+			if player.Position >= c.Capacity {
+				message := fmt.Sprintf("court [%d] has a player in position [%d], so cannot have %d positions", courtID, player.Position, c.Capacity)
+				f.DebugVerbose(message)
+				return codeerror.NewBadRequest(message)
+			}
+		}
+	}
+
+	if val, ok := fields["gameMinutes"]; ok {
+		gameMinutes, ok := val.(float64)
+		if !ok {
+			message := fmt.Sprintf("unexpected type for [%s]: %v", "gameMinutes", val)
+			f.DebugVerbose(message)
+			return codeerror.NewBadRequest(message)
+		}
+		c.GameMinutes = int(gameMinutes)
+
+		err = ValidateGameMinutes(c.GameMinutes)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = c.UpdateCourt(ctx, db)
 	if err != nil {
 		message := fmt.Sprintf("problem updating court: %d", courtID)
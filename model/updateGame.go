@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 )
 
@@ -27,7 +28,7 @@ type GameData struct {
 }
 
 // UpdateGame
-func UpdateGame(db *sql.DB, gameData *GameData) error {
+func UpdateGame(db *sql.DB, gameData *GameData) (err error) {
 	f := functionUpdateGame
 	ctx := context.Background()
 
@@ -37,32 +38,27 @@ func UpdateGame(db *sql.DB, gameData *GameData) error {
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	err = updateGameTx(ctx, db, gameData)
+	err = UpdateGameTx(ctx, tx, gameData)
+	return err
+}
+
+// UpdateGame
+func UpdateGameTx(ctx context.Context, db Querier, gameData *GameData) error {
+	f := functionUpdateGameTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
 	if err != nil {
 		return err
 	}
 
-	count, err := CheckConistency(ctx, db, false)
+	court := Court{ID: gameData.Court}
+	err = court.LoadCourtTx(ctx, db)
 	if err != nil {
-		f.Errorf("Error checking consistency")
-		return err
-	}
-	if count > 0 {
-		message := fmt.Sprintf("Inconsistant data: count: %d", count)
-		f.Errorf(message)
-		err = fmt.Errorf(message)
 		return err
 	}
 
-	return nil
-}
-
-// UpdateGame
-func updateGameTx(ctx context.Context, db *sql.DB, gameData *GameData) error {
-	f := functionUpdateGameTx
-
 	players, err := GetPlayersForCourtAsMap(ctx, db, gameData.Court)
 	if err != nil {
 		message := "Could not list players"
@@ -71,20 +67,27 @@ func updateGameTx(ctx context.Context, db *sql.DB, gameData *GameData) error {
 		return err
 	}
 
-	if len(gameData.Positions) > NumberOfCourtPositions {
+	for index := range gameData.Positions {
+		if index >= court.Capacity {
+			message := fmt.Sprintf("position [%d] is not on court [%d], which has %d positions", index, gameData.Court, court.Capacity)
+			f.Errorf(message)
+			return codeerror.NewBadRequest(message)
+		}
+	}
+
+	if len(gameData.Positions) > court.Capacity {
 		message := fmt.Sprintf("Unexpected number of game positions: game: %d, #positions: %d", gameData.Court, len(gameData.Positions))
 		f.Errorf(message)
-		f.DumpError(err, message)
-		return err
+		return codeerror.NewBadRequest(message)
 	}
 
-	if len(players) > NumberOfCourtPositions {
+	if len(players) > court.Capacity {
 		message := fmt.Sprintf("Unexpected number of players on court: game: %d, #players: %d", gameData.Court, len(players))
 		f.Errorf(message)
-		f.DumpError(err, message)
-		return err
+		return codeerror.NewBadRequest(message)
 	}
 
+	gameEnded := false
 	for index, position := range gameData.Positions {
 
 		var personId *int = nil
@@ -107,6 +110,18 @@ func updateGameTx(ctx context.Context, db *sql.DB, gameData *GameData) error {
 			continue
 		}
 
+		// taking a player off the court ends the game of the current line-up
+		if personId != nil && !gameEnded {
+			_, err = EndGameTx(ctx, db, gameData.Court)
+			if err != nil {
+				message := fmt.Sprintf("Could not record the game on court [%d]", gameData.Court)
+				f.Errorf(message)
+				f.DumpError(err, message)
+				return err
+			}
+			gameEnded = true
+		}
+
 		if personId != nil {
 			err = MakePlayerWaitTx(ctx, db, *personId)
 			if err != nil {
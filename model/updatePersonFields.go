@@ -16,7 +16,7 @@ var (
 )
 
 // UpdatePerson method
-func UpdatePersonFields(db *sql.DB, personID int, fields map[string]interface{}) error {
+func UpdatePersonFields(db *sql.DB, personID int, fields map[string]interface{}) (err error) {
 	f := functionUpdatePersonFields
 	ctx := context.Background()
 
@@ -27,11 +27,11 @@ func UpdatePersonFields(db *sql.DB, personID int, fields map[string]interface{})
 		f.DumpError(err, message)
 		return err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
 	var person FullPerson
 	person.ID = personID
-	err = person.LoadPersonTx(ctx, db)
+	err = person.LoadPersonTx(ctx, tx)
 	if err != nil {
 		message := fmt.Sprintf("could not load person: %d", personID)
 		f.DebugVerbose(message)
@@ -40,7 +40,7 @@ func UpdatePersonFields(db *sql.DB, personID int, fields map[string]interface{})
 		return codeerror.NewInternalServerError(message)
 	}
 
-	err = person.UpdatePersonFields(ctx, db, fields)
+	err = person.UpdatePersonFields(ctx, tx, fields)
 	if err != nil {
 		return err
 	}
@@ -48,7 +48,7 @@ func UpdatePersonFields(db *sql.DB, personID int, fields map[string]interface{})
 	return nil
 }
 
-func (person *FullPerson) UpdatePersonFields(ctx context.Context, db *sql.DB, fields map[string]interface{}) error {
+func (person *FullPerson) UpdatePersonFields(ctx context.Context, db Querier, fields map[string]interface{}) error {
 	f := functionUpdatePersonFieldsTx
 
 	if val, ok := fields["firstname"]; ok {
@@ -114,6 +114,7 @@ func (person *FullPerson) UpdatePersonFields(ctx context.Context, db *sql.DB, fi
 		}
 	}
 
+	status := person.Status
 	if val, ok := fields["status"]; ok {
 		person.Status, ok = val.(string)
 		if !ok {
@@ -131,5 +132,5 @@ func (person *FullPerson) UpdatePersonFields(ctx context.Context, db *sql.DB, fi
 		return codeerror.NewInternalServerError(message)
 	}
 
-	return nil
+	return notifyApprovedTx(ctx, db, person.ID, status, person.Status)
 }
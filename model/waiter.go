@@ -4,32 +4,50 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/rsmaxwell/players-tt-api/internal/codeerror"
 	"github.com/rsmaxwell/players-tt-api/internal/debug"
 )
 
-// Waiter type
+// Waiter type. The queue is in order of priority, highest first, then of place. A paused waiter is
+// sitting out, and keeps their place in the queue without being picked
 type Waiter struct {
-	Person int       `json:"person"`
-	Start  time.Time `json:"start"`
+	Person   int       `json:"person"`
+	Start    time.Time `json:"start"`
+	Paused   bool      `json:"paused"`
+	Place    int       `json:"place"`
+	Priority int       `json:"priority"`
 }
 
 // NullWaiter type
 type NullWaiter struct {
-	Person int
-	Start  sql.NullTime
+	Person   int
+	Start    sql.NullTime
+	Paused   bool
+	Place    int
+	Priority int
 }
 
 type DisplayWaiter struct {
 	PersonId PersonId `json:"personId"`
 	Start    int64    `json:"start"`
+	Paused   bool     `json:"paused"`
+	Priority int      `json:"priority"`
 }
 
 const (
 	// WaitingTable is the name of the waiting table
 	WaitingTable = "waiting"
 	WaitingIndex = "first_waiting"
+
+	// WaitingOrder sorts the waiting table into queue order. The start time and person only break ties
+	// between waiters added at the same moment
+	WaitingOrder = "priority DESC, place, start, person"
+
+	// MaxWaiterPriority is the highest priority of a waiter, and its negative the lowest
+	MaxWaiterPriority = 10
 )
 
 var (
@@ -38,10 +56,16 @@ var (
 	functionListWaitersForPerson = debug.NewFunction(pkg, "ListWaitersForPerson")
 	functionGetFirstWaiter       = debug.NewFunction(pkg, "GetFirstWaiter")
 	functionRemoveWaiter         = debug.NewFunction(pkg, "RemoveWaiter")
+	functionSetWaiterPaused      = debug.NewFunction(pkg, "SetWaiterPaused")
+	functionSetWaiterPausedTx    = debug.NewFunction(pkg, "SetWaiterPausedTx")
+	functionMoveWaiter           = debug.NewFunction(pkg, "MoveWaiter")
+	functionMoveWaiterTx         = debug.NewFunction(pkg, "MoveWaiterTx")
+	functionSetWaiterPriority    = debug.NewFunction(pkg, "SetWaiterPriority")
+	functionSetWaiterPriorityTx  = debug.NewFunction(pkg, "SetWaiterPriorityTx")
 )
 
 // ListWaiters returns the list of waiters
-func ListWaiters(db *sql.DB) ([]Waiter, error) {
+func ListWaiters(db *sql.DB) (listOfWaiters []Waiter, err error) {
 	f := functionListWaiters
 	ctx := context.Background()
 
@@ -52,9 +76,9 @@ func ListWaiters(db *sql.DB) ([]Waiter, error) {
 		f.DumpError(err, message)
 		return nil, err
 	}
-	defer EndTransaction(ctx, tx, db, err)
+	defer EndTransaction(ctx, tx, &err)
 
-	listOfWaiters, err := ListWaitersTx(ctx, db)
+	listOfWaiters, err = ListWaitersTx(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -63,10 +87,11 @@ func ListWaiters(db *sql.DB) ([]Waiter, error) {
 }
 
 // ListWaitersTx returns the list of waiters
-func ListWaitersTx(ctx context.Context, db *sql.DB) ([]Waiter, error) {
+func ListWaitersTx(ctx context.Context, db Querier) ([]Waiter, error) {
 	f := functionListWaitersTx
 
-	sqlStatement := "SELECT * FROM " + WaitingTable + " ORDER BY start ASC"
+	fields := "person, start, paused, place, priority"
+	sqlStatement := "SELECT " + fields + " FROM " + WaitingTable + " ORDER BY " + WaitingOrder
 
 	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
@@ -80,7 +105,7 @@ func ListWaitersTx(ctx context.Context, db *sql.DB) ([]Waiter, error) {
 	for rows.Next() {
 
 		var nw NullWaiter
-		err := rows.Scan(&nw.Person, &nw.Start)
+		err := rows.Scan(&nw.Person, &nw.Start, &nw.Paused, &nw.Place, &nw.Priority)
 		if err != nil {
 			message := "Could not scan the waiter"
 			f.DumpError(err, message)
@@ -89,6 +114,9 @@ func ListWaitersTx(ctx context.Context, db *sql.DB) ([]Waiter, error) {
 
 		var w Waiter
 		w.Person = nw.Person
+		w.Paused = nw.Paused
+		w.Place = nw.Place
+		w.Priority = nw.Priority
 
 		if nw.Start.Valid {
 			w.Start = nw.Start.Time
@@ -107,13 +135,13 @@ func ListWaitersTx(ctx context.Context, db *sql.DB) ([]Waiter, error) {
 }
 
 // ListWaitersForPerson returns the list of waiters for a person
-func ListWaitersForPerson(ctx context.Context, db *sql.DB, id int) ([]Waiter, error) {
+func ListWaitersForPerson(ctx context.Context, db Querier, id int) ([]Waiter, error) {
 	f := functionListWaitersForPerson
 
-	fields := "person, start"
+	fields := "person, start, paused, place, priority"
 	sqlStatement := "SELECT " + fields + " FROM " + WaitingTable + " WHERE person=$1"
 
-	rows, err := db.Query(sqlStatement, id)
+	rows, err := db.QueryContext(ctx, sqlStatement, id)
 	if err != nil {
 		message := "Could not get list the waiters"
 		f.DumpSQLError(err, message, sqlStatement)
@@ -125,7 +153,7 @@ func ListWaitersForPerson(ctx context.Context, db *sql.DB, id int) ([]Waiter, er
 	for rows.Next() {
 
 		var nw NullWaiter
-		err := rows.Scan(&nw.Person, &nw.Start)
+		err := rows.Scan(&nw.Person, &nw.Start, &nw.Paused, &nw.Place, &nw.Priority)
 		if err != nil {
 			message := "Could not scan the waiter"
 			f.DumpError(err, message)
@@ -134,6 +162,9 @@ func ListWaitersForPerson(ctx context.Context, db *sql.DB, id int) ([]Waiter, er
 
 		var w Waiter
 		w.Person = nw.Person
+		w.Paused = nw.Paused
+		w.Place = nw.Place
+		w.Priority = nw.Priority
 
 		if nw.Start.Valid {
 			w.Start = nw.Start.Time
@@ -151,12 +182,12 @@ func ListWaitersForPerson(ctx context.Context, db *sql.DB, id int) ([]Waiter, er
 	return list, nil
 }
 
-// Get first GetFirstWaiter
-func GetFirstWaiter(ctx context.Context, db *sql.DB) (int, error) {
+// GetFirstWaiter returns the waiter at the front of the queue, skipping anyone who is paused
+func GetFirstWaiter(ctx context.Context, db Querier) (int, error) {
 	f := functionGetFirstWaiter
 
 	fields := "person"
-	sqlStatement := "SELECT " + fields + " FROM " + WaitingTable + " ORDER BY start LIMIT 1"
+	sqlStatement := "SELECT " + fields + " FROM " + WaitingTable + " WHERE NOT paused ORDER BY " + WaitingOrder + " LIMIT 1"
 	rows, err := db.QueryContext(ctx, sqlStatement)
 	if err != nil {
 		message := "Could not get the first waiter"
@@ -191,14 +222,15 @@ func GetFirstWaiter(ctx context.Context, db *sql.DB) (int, error) {
 	return id, nil
 }
 
-func AddWaiter(ctx context.Context, db *sql.DB, personID int) error {
+// AddWaiter puts a person at the back of the queue
+func AddWaiter(ctx context.Context, db Querier, personID int) error {
 	f := functionRemoveWaiter
 
 	start := time.Now()
 
-	fields := "person, start"
-	values := "$1, $2"
-	sqlStatement := "INSERT INTO " + WaitingTable + " (" + fields + ") VALUES (" + values + ")"
+	fields := "person, start, place"
+	values := "$1, $2, COALESCE(MAX(place), 0) + 1"
+	sqlStatement := "INSERT INTO " + WaitingTable + " (" + fields + ") SELECT " + values + " FROM " + WaitingTable
 
 	_, err := db.ExecContext(ctx, sqlStatement, personID, start)
 	if err != nil {
@@ -216,20 +248,227 @@ func AddWaiter(ctx context.Context, db *sql.DB, personID int) error {
 		return err
 	}
 
-	return nil
+	return recordAttendanceTx(ctx, db, personID)
 }
 
-func RemoveWaiter(ctx context.Context, db *sql.DB, personID int) error {
+func RemoveWaiter(ctx context.Context, db Querier, personID int) error {
 	f := functionRemoveWaiter
 
 	sqlStatement := "DELETE FROM " + WaitingTable + " WHERE person=$1"
-	rows, err := db.QueryContext(ctx, sqlStatement, personID)
+	_, err := db.ExecContext(ctx, sqlStatement, personID)
 	if err != nil {
 		message := "Could not delete the waiter"
 		f.DumpSQLError(err, message, sqlStatement)
 		return err
 	}
-	defer rows.Close()
+
+	return nil
+}
+
+// SetWaiterPaused pauses or resumes a waiter
+func SetWaiterPaused(db *sql.DB, personID int, paused bool) (err error) {
+	f := functionSetWaiterPaused
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = SetWaiterPausedTx(ctx, tx, personID, paused)
+	return err
+}
+
+// SetWaiterPausedTx pauses or resumes a waiter. The place in the queue is unchanged, so a waiter who
+// resumes is back where they were
+func SetWaiterPausedTx(ctx context.Context, db Querier, personID int, paused bool) error {
+	f := functionSetWaiterPausedTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := "UPDATE " + WaitingTable + " SET paused=$1 WHERE person=$2"
+	result, err := db.ExecContext(ctx, sqlStatement, paused, personID)
+	if err != nil {
+		message := "Could not update the waiter"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		message := "Could not get the count of rows affected"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+	if count == 0 {
+		return codeerror.NewNotFound(fmt.Sprintf("person [%d] is not waiting", personID))
+	}
+
+	return nil
+}
+
+// MoveWaiter moves a waiter to a new index in the queue, and returns the index they end up at
+func MoveWaiter(db *sql.DB, personID int, index int, relative bool) (result int, err error) {
+	f := functionMoveWaiter
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return 0, err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	result, err = MoveWaiterTx(ctx, tx, personID, index, relative)
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// MoveWaiterTx moves a waiter to a new index in the queue, counting from the front, or by that many
+// places when relative, where a negative number moves the waiter forward. An index beyond either end of
+// the queue is taken as that end. The index the waiter ends up at is returned
+func MoveWaiterTx(ctx context.Context, db Querier, personID int, index int, relative bool) (int, error) {
+	f := functionMoveWaiterTx
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	waiters, err := ListWaitersTx(ctx, db)
+	if err != nil {
+		message := "Could not list the waiters"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return 0, err
+	}
+
+	from := -1
+	for i, waiter := range waiters {
+		if waiter.Person == personID {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return 0, codeerror.NewNotFound(fmt.Sprintf("person [%d] is not waiting", personID))
+	}
+
+	to := index
+	if relative {
+		to = from + index
+	}
+
+	waiters, to = MoveInQueue(waiters, from, to)
+
+	sqlStatement := "UPDATE " + WaitingTable + " SET place=$1, priority=$2 WHERE person=$3"
+	for _, waiter := range waiters {
+		_, err = db.ExecContext(ctx, sqlStatement, waiter.Place, waiter.Priority, waiter.Person)
+		if err != nil {
+			message := "Could not update the waiter"
+			f.Errorf(message)
+			f.DumpSQLError(err, message, sqlStatement)
+			return 0, err
+		}
+	}
+
+	return to, nil
+}
+
+// MoveInQueue moves the waiter at one index of the queue to another, and returns the new queue with the
+// places numbered from 1, and the index the waiter ended up at. The moved waiter takes the priority of
+// the waiter now behind them, or in front of them at the back of the queue, so the queue stays in order
+func MoveInQueue(waiters []Waiter, from int, to int) ([]Waiter, int) {
+
+	if to < 0 {
+		to = 0
+	}
+	if to > len(waiters)-1 {
+		to = len(waiters) - 1
+	}
+
+	moved := waiters[from]
+
+	queue := make([]Waiter, 0, len(waiters))
+	queue = append(queue, waiters[:from]...)
+	queue = append(queue, waiters[from+1:]...)
+	queue = append(queue[:to], append([]Waiter{moved}, queue[to:]...)...)
+
+	if to+1 < len(queue) {
+		queue[to].Priority = queue[to+1].Priority
+	} else if to > 0 {
+		queue[to].Priority = queue[to-1].Priority
+	}
+
+	for i := range queue {
+		queue[i].Place = i + 1
+	}
+
+	return queue, to
+}
+
+// SetWaiterPriority changes the priority of a waiter
+func SetWaiterPriority(db *sql.DB, personID int, priority int) (err error) {
+	f := functionSetWaiterPriority
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		message := "Could not begin a new transaction"
+		f.DumpError(err, message)
+		return err
+	}
+	defer EndTransaction(ctx, tx, &err)
+
+	err = SetWaiterPriorityTx(ctx, tx, personID, priority)
+	return err
+}
+
+// SetWaiterPriorityTx changes the priority of a waiter. Waiters with a higher priority are ahead in the
+// queue, and the waiter keeps their place among the waiters of the new priority
+func SetWaiterPriorityTx(ctx context.Context, db Querier, personID int, priority int) error {
+	f := functionSetWaiterPriorityTx
+
+	if priority < -MaxWaiterPriority || priority > MaxWaiterPriority {
+		return codeerror.NewBadRequest(fmt.Sprintf("a priority is between %d and %d, not %d", -MaxWaiterPriority, MaxWaiterPriority, priority))
+	}
+
+	_, err := RequireOpenSessionTx(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := "UPDATE " + WaitingTable + " SET priority=$1 WHERE person=$2"
+	result, err := db.ExecContext(ctx, sqlStatement, priority, personID)
+	if err != nil {
+		message := "Could not update the waiter"
+		f.Errorf(message)
+		f.DumpSQLError(err, message, sqlStatement)
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		message := "Could not get the count of rows affected"
+		f.Errorf(message)
+		f.DumpError(err, message)
+		return err
+	}
+	if count == 0 {
+		return codeerror.NewNotFound(fmt.Sprintf("person [%d] is not waiting", personID))
+	}
 
 	return nil
 }
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+func TestPausedWaiter(t *testing.T) {
+
+	teardown, db, _ := Setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+
+	first, err := GetFirstWaiter(ctx, db)
+	if err != nil {
+		t.Log("Could not get the first waiter")
+		t.FailNow()
+	}
+	if first == 0 {
+		t.Log("Could not find any waiters")
+		t.FailNow()
+	}
+
+	err = SetWaiterPaused(db, first, true)
+	if err != nil {
+		t.Log("Could not pause the waiter")
+		t.FailNow()
+	}
+
+	next, err := GetFirstWaiter(ctx, db)
+	if err != nil {
+		t.Log("Could not get the first waiter")
+		t.FailNow()
+	}
+	if next == first {
+		t.Logf("The paused waiter [%d] was not skipped", first)
+		t.FailNow()
+	}
+
+	err = SetWaiterPaused(db, first, false)
+	if err != nil {
+		t.Log("Could not resume the waiter")
+		t.FailNow()
+	}
+
+	next, err = GetFirstWaiter(ctx, db)
+	if err != nil {
+		t.Log("Could not get the first waiter")
+		t.FailNow()
+	}
+	if next != first {
+		t.Logf("Unexpected first waiter: %d, expected: %d", next, first)
+		t.FailNow()
+	}
+}
+
+func TestMoveInQueue(t *testing.T) {
+
+	queue := func() []Waiter {
+		return []Waiter{
+			{Person: 1, Place: 1, Priority: 5},
+			{Person: 2, Place: 2},
+			{Person: 3, Place: 3},
+			{Person: 4, Place: 4},
+		}
+	}
+
+	cases := []struct {
+		from     int
+		to       int
+		index    int
+		people   []int
+		priority int
+	}{
+		{3, 0, 0, []int{4, 1, 2, 3}, 5},
+		{3, 1, 1, []int{1, 4, 2, 3}, 0},
+		{0, 3, 3, []int{2, 3, 4, 1}, 0},
+		{1, -2, 0, []int{2, 1, 3, 4}, 5},
+		{1, 9, 3, []int{1, 3, 4, 2}, 0},
+	}
+
+	for _, c := range cases {
+		result, index := MoveInQueue(queue(), c.from, c.to)
+		if index != c.index {
+			t.Logf("Moving %d to %d: unexpected index: %d, expected: %d", c.from, c.to, index, c.index)
+			t.FailNow()
+		}
+
+		for i, waiter := range result {
+			if waiter.Person != c.people[i] || waiter.Place != i+1 {
+				t.Logf("Moving %d to %d: unexpected waiter at %d: person %d, place %d", c.from, c.to, i, waiter.Person, waiter.Place)
+				t.FailNow()
+			}
+		}
+
+		if result[index].Priority != c.priority {
+			t.Logf("Moving %d to %d: unexpected priority: %d, expected: %d", c.from, c.to, result[index].Priority, c.priority)
+			t.FailNow()
+		}
+	}
+}